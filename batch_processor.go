@@ -0,0 +1,372 @@
+package coretracer
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	otel "go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Overflow policies decide what happens to an ended span when the queue is full.
+const (
+	// OverflowDropNewest drops the span that has just ended.
+	OverflowDropNewest = "drop_newest"
+	// OverflowDropOldest drops the span waiting in the queue for the longest time.
+	OverflowDropOldest = "drop_oldest"
+	// OverflowBlock blocks the goroutine ending the span until there is room in the queue,
+	// for QueueConfig.BlockTimeout at most, then drops the span.
+	OverflowBlock = "block"
+)
+
+// Batching defaults match the ones of the OpenTelemetry SDK batcher.
+const (
+	defaultQueueSize     = 2048
+	defaultBatchSize     = 512
+	defaultExportTimeout = 30 * time.Second
+	defaultScheduleDelay = 5 * time.Second
+	defaultBlockTimeout  = time.Second
+
+	// exporterShutdownTimeout limits the exporter shutdown once the processor shutdown deadline is over.
+	exporterShutdownTimeout = time.Second
+)
+
+var errProcessorStopped = errors.New("span processor is shut down")
+
+// QueueConfig configures the queue of ended spans and the batches they are exported in.
+// Every exporter has its own queue. The zero value uses the OpenTelemetry SDK defaults.
+// Spans dropped for any reason are counted in Stats.
+type QueueConfig struct {
+	// Size is the maximum number of spans waiting for export. Defaults to 2048.
+	Size int
+	// BatchSize is the maximum number of spans in one export, up to Size. Defaults to 512.
+	BatchSize int
+	// ExportTimeout limits the duration of one export. Defaults to 30s.
+	ExportTimeout time.Duration
+	// ScheduleDelay is the delay between exports of incomplete batches. Defaults to 5s.
+	ScheduleDelay time.Duration
+	// OverflowPolicy is one of Overflow* constants, defaults to OverflowDropNewest.
+	OverflowPolicy string
+	// BlockTimeout limits the wait of OverflowBlock. Defaults to 1s.
+	BlockTimeout time.Duration
+}
+
+type batchOptions struct {
+	queueSize      int
+	batchSize      int
+	exportTimeout  time.Duration
+	scheduleDelay  time.Duration
+	overflowPolicy string
+	blockTimeout   time.Duration
+}
+
+// batchOptions applies the defaults to the queue config.
+func (c *Config) batchOptions() batchOptions {
+	q := c.Queue
+
+	opts := batchOptions{
+		queueSize:      cmp.Or(max(q.Size, 0), defaultQueueSize),
+		batchSize:      cmp.Or(max(q.BatchSize, 0), defaultBatchSize),
+		exportTimeout:  cmp.Or(max(q.ExportTimeout, 0), defaultExportTimeout),
+		scheduleDelay:  cmp.Or(max(q.ScheduleDelay, 0), defaultScheduleDelay),
+		overflowPolicy: cmp.Or(q.OverflowPolicy, OverflowDropNewest),
+		blockTimeout:   cmp.Or(max(q.BlockTimeout, 0), defaultBlockTimeout),
+	}
+
+	opts.batchSize = min(opts.batchSize, opts.queueSize)
+
+	switch opts.overflowPolicy {
+	case OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+	default:
+		c.Logger.Warn("coretracer: unknown queue overflow policy, falling back to drop_newest", "policy", opts.overflowPolicy)
+		opts.overflowPolicy = OverflowDropNewest
+	}
+
+	return opts
+}
+
+var _ sdktrace.SpanProcessor = (*batchSpanProcessor)(nil)
+
+// batchSpanProcessor queues ended spans and exports them in batches from a background loop.
+// Unlike the SDK batcher it accounts every span it drops, see Stats.
+type batchSpanProcessor struct {
+	// exporter is used by the loop, it's changed under swapMux.
+	exporter sdktrace.SpanExporter
+	opts     batchOptions
+
+	// nextExporter is handed over by swapExporter, the loop switches to it between exports.
+	swapMux      sync.Mutex
+	nextExporter sdktrace.SpanExporter
+
+	queue  chan sdktrace.ReadOnlySpan
+	flushC chan chan struct{}
+	swapC  chan struct{}
+
+	stopMux  sync.RWMutex
+	stopped  bool
+	stopC    chan struct{}
+	doneC    chan struct{}
+	stopOnce sync.Once
+}
+
+func newBatchSpanProcessor(exporter sdktrace.SpanExporter, opts batchOptions) *batchSpanProcessor {
+	p := &batchSpanProcessor{
+		exporter: exporter,
+		opts:     opts,
+		queue:    make(chan sdktrace.ReadOnlySpan, opts.queueSize),
+		flushC:   make(chan chan struct{}),
+		swapC:    make(chan struct{}, 1),
+		stopC:    make(chan struct{}),
+		doneC:    make(chan struct{}),
+	}
+
+	go p.loop()
+
+	return p
+}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (p *batchSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (p *batchSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+
+	p.stopMux.RLock()
+	defer p.stopMux.RUnlock()
+
+	if p.stopped {
+		globalStats.spansDropped.Add(1)
+		return
+	}
+
+	select {
+	case p.queue <- s:
+		return
+	default:
+	}
+
+	switch p.opts.overflowPolicy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case p.queue <- s:
+				return
+			default:
+			}
+
+			// the loop might have taken it meanwhile, then there is room anyway
+			select {
+			case <-p.queue:
+				globalStats.spansDropped.Add(1)
+			default:
+			}
+		}
+	case OverflowBlock:
+		timer := time.NewTimer(p.opts.blockTimeout)
+		defer timer.Stop()
+
+		select {
+		case p.queue <- s:
+		case <-timer.C:
+			globalStats.spansDropped.Add(1)
+		}
+	default:
+		globalStats.spansDropped.Add(1)
+	}
+}
+
+// ForceFlush implements sdktrace.SpanProcessor.
+func (p *batchSpanProcessor) ForceFlush(ctx context.Context) error {
+	flushedC := make(chan struct{})
+
+	select {
+	case p.flushC <- flushedC:
+	case <-p.doneC:
+		return errProcessorStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushedC:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown implements sdktrace.SpanProcessor. Exports the queued spans and shuts down the exporter.
+func (p *batchSpanProcessor) Shutdown(ctx context.Context) error {
+	var err error
+
+	p.stopOnce.Do(func() {
+		p.stopMux.Lock()
+		p.stopped = true
+		p.stopMux.Unlock()
+
+		close(p.stopC)
+
+		select {
+		case <-p.doneC:
+		case <-ctx.Done():
+			// the loop is stuck in an export, the exporter is shut down anyway to release
+			// its connections, with a short deadline of its own
+			shutdownCtx, cancelFn := context.WithTimeout(context.WithoutCancel(ctx), exporterShutdownTimeout)
+			defer cancelFn()
+
+			err = errors.Join(ctx.Err(), p.shutdownExporter(shutdownCtx))
+
+			return
+		}
+
+		err = p.shutdownExporter(ctx)
+	})
+
+	return err
+}
+
+// shutdownExporter shuts down the exporter, along with the one handed over but not switched to yet.
+func (p *batchSpanProcessor) shutdownExporter(ctx context.Context) error {
+	p.swapMux.Lock()
+	exporter, next := p.exporter, p.nextExporter
+	p.nextExporter = nil
+	p.swapMux.Unlock()
+
+	err := exporter.Shutdown(ctx)
+	if next != nil {
+		err = errors.Join(err, next.Shutdown(ctx))
+	}
+
+	return err
+}
+
+// swapExporter hands the exporter over to the loop, that switches to it once the export in progress
+// is over and shuts the previous one down, so the caller never waits for an export.
+// Queued spans are exported by the new exporter.
+func (p *batchSpanProcessor) swapExporter(exporter sdktrace.SpanExporter) {
+	p.stopMux.RLock()
+	defer p.stopMux.RUnlock()
+
+	if p.stopped {
+		go shutdownExporters([]sdktrace.SpanExporter{exporter})
+		return
+	}
+
+	p.swapMux.Lock()
+	replaced := p.nextExporter
+	p.nextExporter = exporter
+	p.swapMux.Unlock()
+
+	// handed over before, but never switched to
+	if replaced != nil {
+		go shutdownExporters([]sdktrace.SpanExporter{replaced})
+	}
+
+	select {
+	case p.swapC <- struct{}{}:
+	default:
+	}
+}
+
+// switchExporter switches to the exporter handed over by swapExporter, if any, and shuts the previous one down.
+// The previous exporter is done before the next export, so e.g. an OTLP spool is handed over in order.
+func (p *batchSpanProcessor) switchExporter() {
+	p.swapMux.Lock()
+	prev, next := p.exporter, p.nextExporter
+	if next != nil {
+		p.exporter, p.nextExporter = next, nil
+	}
+	p.swapMux.Unlock()
+
+	if next == nil {
+		return
+	}
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), p.opts.exportTimeout)
+	defer cancelFn()
+
+	_ = prev.Shutdown(ctx)
+}
+
+func (p *batchSpanProcessor) loop() {
+	defer close(p.doneC)
+
+	ticker := time.NewTicker(p.opts.scheduleDelay)
+	defer ticker.Stop()
+
+	batch := make([]sdktrace.ReadOnlySpan, 0, p.opts.batchSize)
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) < p.opts.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		case flushedC := <-p.flushC:
+			batch = p.drain(batch)
+			close(flushedC)
+			continue
+		case <-p.swapC:
+			p.switchExporter()
+			continue
+		case <-p.stopC:
+			p.switchExporter()
+			p.drain(batch)
+			return
+		}
+
+		batch = p.export(batch)
+	}
+}
+
+// drain exports the batch along with everything left in the queue.
+func (p *batchSpanProcessor) drain(batch []sdktrace.ReadOnlySpan) []sdktrace.ReadOnlySpan {
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.opts.batchSize {
+				batch = p.export(batch)
+			}
+		default:
+			return p.export(batch)
+		}
+	}
+}
+
+// export sends the batch, returning it emptied for reuse.
+func (p *batchSpanProcessor) export(batch []sdktrace.ReadOnlySpan) []sdktrace.ReadOnlySpan {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	// the swap might be waiting behind the batch in the loop
+	p.switchExporter()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), p.opts.exportTimeout)
+	defer cancelFn()
+
+	err := p.exporter.ExportSpans(ctx, batch)
+
+	switch {
+	case errors.Is(err, ErrSpooled):
+		globalStats.exportSpooled(len(batch))
+	case err != nil:
+		globalStats.exportFailed(err, len(batch))
+		otel.Handle(err)
+	default:
+		globalStats.exportSucceeded(len(batch))
+	}
+
+	clear(batch)
+
+	return batch[:0]
+}
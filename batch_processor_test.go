@@ -0,0 +1,177 @@
+package coretracer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// gateExporter holds exports until the gate is opened, then records spans.
+type gateExporter struct {
+	*tracetest.InMemoryExporter
+	gateC     chan struct{}
+	openOnce  sync.Once
+	exportedC chan struct{}
+	shutdown  atomic.Bool
+}
+
+func newGateExporter() *gateExporter {
+	return &gateExporter{
+		InMemoryExporter: tracetest.NewInMemoryExporter(),
+		gateC:            make(chan struct{}),
+		exportedC:        make(chan struct{}, 128),
+	}
+}
+
+func (e *gateExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	<-e.gateC
+
+	err := e.InMemoryExporter.ExportSpans(ctx, spans)
+	e.exportedC <- struct{}{}
+
+	return err
+}
+
+func (e *gateExporter) Shutdown(ctx context.Context) error {
+	e.shutdown.Store(true)
+	return e.InMemoryExporter.Shutdown(ctx)
+}
+
+func (e *gateExporter) open() {
+	e.openOnce.Do(func() { close(e.gateC) })
+}
+
+// newTestProcessor returns a processor with its first span stuck in the export,
+// so the queue fills up deterministically.
+func newTestProcessor(t *testing.T, queue QueueConfig) (*gateExporter, *sdktrace.TracerProvider, func(name string)) {
+	exporter := newGateExporter()
+
+	cfg := validateConfig(&Config{Queue: queue})
+	processor := newBatchSpanProcessor(exporter, cfg.batchOptions())
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+
+	t.Cleanup(func() {
+		exporter.open()
+		_ = provider.Shutdown(context.Background())
+	})
+
+	endSpan := func(name string) {
+		_, span := provider.Tracer("test").Start(context.Background(), name)
+		span.End()
+	}
+
+	endSpan("stuck")
+	require.Eventually(t, func() bool {
+		return len(processor.queue) == 0
+	}, time.Second, time.Millisecond)
+
+	return exporter, provider, endSpan
+}
+
+func TestBatchSpanProcessor_OverflowPolicies(t *testing.T) {
+	testCases := []struct {
+		policy   string
+		expected []string
+	}{
+		{OverflowDropNewest, []string{"stuck", "first", "second"}},
+		{OverflowDropOldest, []string{"stuck", "third", "fourth"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.policy, func(t *testing.T) {
+			exporter, provider, endSpan := newTestProcessor(t, QueueConfig{
+				Size:           2,
+				BatchSize:      1,
+				OverflowPolicy: tc.policy,
+			})
+
+			before := Stats()
+
+			for _, name := range []string{"first", "second", "third", "fourth"} {
+				endSpan(name)
+			}
+
+			require.EqualValues(t, 2, Stats().SpansDropped-before.SpansDropped)
+
+			exporter.open()
+			require.NoError(t, provider.ForceFlush(context.Background()))
+			require.Equal(t, tc.expected, spanNames(exporter.GetSpans()))
+		})
+	}
+}
+
+func TestBatchSpanProcessor_OverflowBlock(t *testing.T) {
+	exporter, provider, endSpan := newTestProcessor(t, QueueConfig{
+		Size:           1,
+		BatchSize:      1,
+		OverflowPolicy: OverflowBlock,
+		BlockTimeout:   50 * time.Millisecond,
+	})
+
+	before := Stats()
+
+	endSpan("queued")
+
+	startedAt := time.Now()
+	endSpan("timed-out")
+	require.GreaterOrEqual(t, time.Since(startedAt), 50*time.Millisecond, "Expected the span end to block")
+	require.EqualValues(t, 1, Stats().SpansDropped-before.SpansDropped)
+
+	// room is made while blocked
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		exporter.open()
+	}()
+
+	endSpan("waited")
+	require.EqualValues(t, 1, Stats().SpansDropped-before.SpansDropped)
+
+	require.NoError(t, provider.ForceFlush(context.Background()))
+	require.Equal(t, []string{"stuck", "queued", "waited"}, spanNames(exporter.GetSpans()))
+}
+
+func TestBatchSpanProcessor_ShutdownDeadline(t *testing.T) {
+	exporter := newGateExporter()
+	t.Cleanup(exporter.open)
+
+	processor := newBatchSpanProcessor(exporter, validateConfig(&Config{
+		Queue: QueueConfig{BatchSize: 1},
+	}).batchOptions())
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+
+	_, span := provider.Tracer("test").Start(context.Background(), "stuck")
+	span.End()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelFn()
+
+	require.ErrorIs(t, processor.Shutdown(ctx), context.DeadlineExceeded)
+	require.True(t, exporter.shutdown.Load(), "Expected the exporter to be shut down despite the stuck export")
+}
+
+func TestConfig_BatchOptions(t *testing.T) {
+	opts := validateConfig(&Config{}).batchOptions()
+	require.Equal(t, batchOptions{
+		queueSize:      defaultQueueSize,
+		batchSize:      defaultBatchSize,
+		exportTimeout:  defaultExportTimeout,
+		scheduleDelay:  defaultScheduleDelay,
+		overflowPolicy: OverflowDropNewest,
+		blockTimeout:   defaultBlockTimeout,
+	}, opts)
+
+	opts = validateConfig(&Config{Queue: QueueConfig{
+		Size:           100,
+		BatchSize:      1000,
+		OverflowPolicy: "drop_everything",
+	}}).batchOptions()
+	require.Equal(t, 100, opts.queueSize)
+	require.Equal(t, 100, opts.batchSize, "Expected batch size to be capped by the queue size")
+	require.Equal(t, OverflowDropNewest, opts.overflowPolicy)
+}
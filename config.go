@@ -1,22 +1,76 @@
 package coretracer
 
 import (
+	"bytes"
 	"log/slog"
+	"maps"
+	"slices"
 	"time"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	CollectorProtocolGRPC         = "grpc"
+	CollectorProtocolHTTPProtobuf = "http/protobuf"
+	CollectorProtocolHTTPJSON     = "http/json"
 )
 
 type Config struct {
-	Enabled               bool
-	EnvName               string
-	ServiceName           string
-	ServiceVersion        string
-	ClusterID             string
-	CollectorDSN          string
-	CollectorSecureSSL    bool
-	CollectorHeaders      map[string]string
+	Enabled        bool
+	EnvName        string
+	ServiceName    string
+	ServiceVersion string
+	ClusterID      string
+	// ResourceAttributes are extra attributes describing the service, attached to every exported span,
+	// e.g. {"k8s.namespace.name": "prod"}.
+	ResourceAttributes map[string]string
+	// ResourceDetectors add detected attributes to the resource, e.g. DefaultResourceDetectors()
+	// or user-defined ones. Explicitly configured attributes win over the detected.
+	ResourceDetectors  []resource.Detector
+	CollectorDSN       string
+	CollectorSecureSSL bool
+	CollectorHeaders   map[string]string
+	// CollectorTLS configures CAs, the client certificate for mutual TLS and other TLS settings,
+	// applied when CollectorSecureSSL is set.
+	CollectorTLS TLSConfig
+	// CollectorProtocol selects the OTLP wire protocol, one of CollectorProtocol* constants.
+	// HTTP exporters use it to pick between protobuf (default) and JSON encodings.
+	CollectorProtocol string
+	// CollectorURLPath overrides the URL path of HTTP exporters, e.g. "/v1/traces".
+	CollectorURLPath      string
 	StuckFunctionWatchdog bool
 	StuckFunctionTimeout  time.Duration
-	Logger                BasicLogger
+	// InitRetryInterval enables background retries of EnableE, when the exporter fails to initialize.
+	InitRetryInterval time.Duration
+	// Sampling configures head sampling applied by the exporter init.
+	Sampling SamplingConfig
+	// TailSampling keeps traces with errors, stuck or slow spans, see TailSamplingConfig.
+	TailSampling TailSamplingConfig
+	// SpanMetrics derives call, error and duration metrics from spans, see SpanMetricsConfig.
+	SpanMetrics SpanMetricsConfig
+	// Propagators lists the formats Inject and Extract use across process boundaries,
+	// any of Propagator* constants. Defaults to W3C trace context and baggage.
+	Propagators []string
+	// CollectorSpool enables the on-disk spool of OTLP exporters, see SpoolConfig.
+	CollectorSpool SpoolConfig
+	// Queue configures the span queue and batching in front of every exporter.
+	Queue  QueueConfig
+	Logger BasicLogger
+}
+
+// SpoolConfig configures the on-disk spool that keeps span batches while the collector
+// is unreachable, and replays them in order once it's back. The zero value disables it.
+type SpoolConfig struct {
+	// Dir is the directory where batches are stored, the spool is enabled when set.
+	Dir string
+	// MaxSize limits the total size of spooled batches in bytes, the oldest are discarded first.
+	// Defaults to 64MiB.
+	MaxSize int64
+	// MaxAge discards batches that were not replayed in time. Defaults to 24h.
+	MaxAge time.Duration
+	// ReplayInterval is the delay between replay attempts. Defaults to 5s.
+	ReplayInterval time.Duration
 }
 
 type BasicLogger interface {
@@ -55,6 +109,25 @@ func (c *Config) GlobalTagsMap() map[string]string {
 	return globalTags
 }
 
+// clone returns a deep copy of the config, so the caller changing its maps and slices
+// doesn't change the config the tracer runs with. Interfaces, like the logger, are shared.
+func (c *Config) clone() *Config {
+	cloned := *c
+
+	cloned.ResourceAttributes = maps.Clone(c.ResourceAttributes)
+	cloned.ResourceDetectors = slices.Clone(c.ResourceDetectors)
+	cloned.CollectorHeaders = maps.Clone(c.CollectorHeaders)
+	cloned.CollectorTLS.CAPEM = bytes.Clone(c.CollectorTLS.CAPEM)
+	cloned.CollectorTLS.CertPEM = bytes.Clone(c.CollectorTLS.CertPEM)
+	cloned.CollectorTLS.KeyPEM = bytes.Clone(c.CollectorTLS.KeyPEM)
+	cloned.Sampling.SpanNameRatios = maps.Clone(c.Sampling.SpanNameRatios)
+	cloned.SpanMetrics.TagKeys = slices.Clone(c.SpanMetrics.TagKeys)
+	cloned.SpanMetrics.DurationBuckets = slices.Clone(c.SpanMetrics.DurationBuckets)
+	cloned.Propagators = slices.Clone(c.Propagators)
+
+	return &cloned
+}
+
 // DefaultConfig returns a default config with sane defaults.
 func DefaultConfig() *Config {
 	return validateConfig(nil)
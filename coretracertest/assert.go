@@ -0,0 +1,188 @@
+package coretracertest
+
+import (
+	"fmt"
+	"reflect"
+
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// SpanAssert provides chainable assertions over a recorded span.
+// Failed assertions are reported with t.Errorf, so the test keeps going.
+type SpanAssert struct {
+	rec  *Recorder
+	span sdktrace.ReadOnlySpan
+}
+
+// Span returns the asserted span, nil if it has not been found.
+func (a *SpanAssert) Span() sdktrace.ReadOnlySpan {
+	return a.span
+}
+
+// IsRoot asserts that the span has been started right from the recorder's context.
+func (a *SpanAssert) IsRoot() *SpanAssert {
+	a.rec.t.Helper()
+
+	if a.span == nil {
+		return a
+	}
+
+	if a.span.Parent().SpanID() != a.rec.parentSpanContext().SpanID() {
+		a.rec.t.Errorf("coretracertest: expected span %q to be a root span, its parent is %q",
+			a.span.Name(), a.parentName())
+	}
+
+	return a
+}
+
+// HasParent asserts that the span is a direct child of a span with the given name.
+func (a *SpanAssert) HasParent(name string) *SpanAssert {
+	a.rec.t.Helper()
+
+	if a.span == nil {
+		return a
+	}
+
+	if parentName := a.parentName(); parentName != name {
+		a.rec.t.Errorf("coretracertest: expected span %q to have parent %q, got %q",
+			a.span.Name(), name, parentName)
+	}
+
+	return a
+}
+
+func (a *SpanAssert) parentName() string {
+	parentID := a.span.Parent().SpanID()
+
+	for _, span := range a.rec.Spans() {
+		if span.SpanContext().SpanID() == parentID {
+			return span.Name()
+		}
+	}
+
+	return ""
+}
+
+// HasTag asserts that the span has a tag with the given key and value.
+// Values are compared as coretracer would report them, e.g. int(1) matches int64(1).
+func (a *SpanAssert) HasTag(k string, v any) *SpanAssert {
+	a.rec.t.Helper()
+
+	if a.span == nil {
+		return a
+	}
+
+	for _, attr := range a.span.Attributes() {
+		if string(attr.Key) != k {
+			continue
+		}
+
+		if actual := attr.Value.AsInterface(); !sameValue(actual, v) {
+			a.rec.t.Errorf("coretracertest: expected span %q to have tag %s=%v, got %s=%v",
+				a.span.Name(), k, v, k, actual)
+		}
+
+		return a
+	}
+
+	a.rec.t.Errorf("coretracertest: expected span %q to have tag %s=%v, tag is missing", a.span.Name(), k, v)
+
+	return a
+}
+
+func sameValue(actual, expected any) bool {
+	if expected != nil {
+		if rv := reflect.ValueOf(expected); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			expected = rv.Elem().Interface()
+		}
+	}
+
+	return fmt.Sprint(actual) == fmt.Sprint(expected)
+}
+
+// HasStatus asserts the status code of the span.
+func (a *SpanAssert) HasStatus(code otelcodes.Code) *SpanAssert {
+	a.rec.t.Helper()
+
+	if a.span == nil {
+		return a
+	}
+
+	if status := a.span.Status(); status.Code != code {
+		a.rec.t.Errorf("coretracertest: expected span %q to have status %s, got %s",
+			a.span.Name(), code, status.Code)
+	}
+
+	return a
+}
+
+// HasStatusError asserts that the span has been marked as failed.
+func (a *SpanAssert) HasStatusError() *SpanAssert {
+	a.rec.t.Helper()
+
+	return a.HasStatus(otelcodes.Error)
+}
+
+// HasStatusOk asserts that the span has been ended with a success.
+func (a *SpanAssert) HasStatusOk() *SpanAssert {
+	a.rec.t.Helper()
+
+	return a.HasStatus(otelcodes.Ok)
+}
+
+// HasException asserts that the span has recorded an error with the given message.
+func (a *SpanAssert) HasException(message string) *SpanAssert {
+	a.rec.t.Helper()
+
+	if a.span == nil {
+		return a
+	}
+
+	var messages []string
+
+	for _, event := range a.span.Events() {
+		if event.Name != "exception" {
+			continue
+		}
+
+		for _, attr := range event.Attributes {
+			if attr.Key != "exception.message" {
+				continue
+			}
+
+			if attr.Value.AsString() == message {
+				return a
+			}
+
+			messages = append(messages, attr.Value.AsString())
+		}
+	}
+
+	a.rec.t.Errorf("coretracertest: expected span %q to have exception %q, got %q",
+		a.span.Name(), message, messages)
+
+	return a
+}
+
+// HasChildCount asserts the amount of direct children of the span.
+func (a *SpanAssert) HasChildCount(n int) *SpanAssert {
+	a.rec.t.Helper()
+
+	if a.span == nil {
+		return a
+	}
+
+	var count int
+	for _, span := range a.rec.Spans() {
+		if span.Parent().SpanID() == a.span.SpanContext().SpanID() {
+			count++
+		}
+	}
+
+	if count != n {
+		a.rec.t.Errorf("coretracertest: expected span %q to have %d children, got %d", a.span.Name(), n, count)
+	}
+
+	return a
+}
@@ -0,0 +1,167 @@
+// Package coretracertest enables coretracer against an in-memory span recorder
+// scoped to a test, and provides fluent assertions over the recorded spans.
+//
+//	func TestSomething(t *testing.T) {
+//		t.Parallel()
+//
+//		rec := coretracertest.New(t)
+//		ctx := rec.Context()
+//
+//		svc.SomeFunc(ctx)
+//
+//		rec.Span("SomeFunc").HasTag("svc", "myService").HasChildCount(1)
+//		rec.Span("SomeOtherFunc").HasParent("SomeFunc").HasStatusError().HasException("boom")
+//	}
+//
+// Every recorder owns a unique trace, only spans started from its context are recorded,
+// so tests running in parallel never see each other's spans. Spans that start a new root
+// trace, like Traceless(nil), are not attributed to any recorder.
+package coretracertest
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	otel "go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltracer "go.opentelemetry.io/otel/trace"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+var (
+	storeMux  = new(sync.Mutex)
+	store     *spanStore
+	storeRefs int
+)
+
+// New enables coretracer for the lifetime of the test, coretracer is closed by t.Cleanup
+// once the last recorder of the running tests is done.
+func New(t testing.TB) *Recorder {
+	t.Helper()
+
+	rec := &Recorder{
+		t:       t,
+		traceID: newTraceID(),
+	}
+
+	acquireStore(rec.traceID)
+	t.Cleanup(func() {
+		releaseStore(rec.traceID)
+	})
+
+	return rec
+}
+
+func acquireStore(traceID oteltracer.TraceID) {
+	storeMux.Lock()
+	defer storeMux.Unlock()
+
+	if storeRefs == 0 {
+		store = newSpanStore()
+
+		coretracer.Enable(&coretracer.Config{
+			EnvName:     "test",
+			ServiceName: "coretracertest",
+		}, func(cfg *coretracer.Config) coretracer.ExporterShutdownFn {
+			traceProvider := sdktrace.NewTracerProvider(
+				sdktrace.WithSampler(sdktrace.AlwaysSample()),
+				sdktrace.WithSpanProcessor(store),
+			)
+
+			otel.SetTracerProvider(traceProvider)
+
+			return traceProvider.Shutdown
+		})
+	}
+
+	storeRefs++
+	store.register(traceID)
+}
+
+func releaseStore(traceID oteltracer.TraceID) {
+	storeMux.Lock()
+	defer storeMux.Unlock()
+
+	store.unregister(traceID)
+	storeRefs--
+
+	if storeRefs == 0 {
+		coretracer.Close()
+		store = nil
+	}
+}
+
+func currentStore() *spanStore {
+	storeMux.Lock()
+	defer storeMux.Unlock()
+
+	return store
+}
+
+func newTraceID() oteltracer.TraceID {
+	var traceID oteltracer.TraceID
+	_, _ = rand.Read(traceID[:])
+
+	return traceID
+}
+
+var _ sdktrace.SpanProcessor = (*spanStore)(nil)
+
+// spanStore keeps ended spans of registered traces, other spans are ignored.
+type spanStore struct {
+	mux   sync.RWMutex
+	spans map[oteltracer.TraceID][]sdktrace.ReadOnlySpan
+}
+
+func newSpanStore() *spanStore {
+	return &spanStore{
+		spans: make(map[oteltracer.TraceID][]sdktrace.ReadOnlySpan),
+	}
+}
+
+func (s *spanStore) register(traceID oteltracer.TraceID) {
+	s.mux.Lock()
+	s.spans[traceID] = []sdktrace.ReadOnlySpan{}
+	s.mux.Unlock()
+}
+
+func (s *spanStore) unregister(traceID oteltracer.TraceID) {
+	s.mux.Lock()
+	delete(s.spans, traceID)
+	s.mux.Unlock()
+}
+
+func (s *spanStore) get(traceID oteltracer.TraceID) []sdktrace.ReadOnlySpan {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return append([]sdktrace.ReadOnlySpan(nil), s.spans[traceID]...)
+}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (s *spanStore) OnStart(parent context.Context, span sdktrace.ReadWriteSpan) {}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (s *spanStore) OnEnd(span sdktrace.ReadOnlySpan) {
+	traceID := span.SpanContext().TraceID()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if spans, ok := s.spans[traceID]; ok {
+		s.spans[traceID] = append(spans, span)
+	}
+}
+
+// Shutdown implements sdktrace.SpanProcessor.
+func (s *spanStore) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// ForceFlush implements sdktrace.SpanProcessor.
+func (s *spanStore) ForceFlush(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,114 @@
+package coretracertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+func serviceCall(ctx context.Context, fail bool) {
+	defer coretracer.TraceWithName(&ctx, "serviceCall", coretracer.NewTag("svc", "myService"))()
+
+	dbCall(ctx, fail)
+	coretracer.WithTags(ctx, coretracer.NewTag("block_height", 42))
+}
+
+func dbCall(ctx context.Context, fail bool) {
+	defer coretracer.TraceWithName(&ctx, "dbCall")()
+
+	if fail {
+		coretracer.TraceError(ctx, errors.New("db is down"))
+	}
+}
+
+func TestRecorder_Assertions(t *testing.T) {
+	rec := New(t)
+
+	serviceCall(rec.Context(), true)
+
+	rec.Span("serviceCall").
+		IsRoot().
+		HasTag("svc", "myService").
+		HasTag("block_height", 42).
+		HasStatusOk().
+		HasChildCount(1)
+
+	rec.Span("dbCall").
+		HasParent("serviceCall").
+		HasStatusError().
+		HasException("db is down").
+		HasChildCount(0)
+
+	rec.NoSpan("otherCall")
+}
+
+func TestRecorder_ParallelTestsAreIsolated(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		t.Run(fmt.Sprintf("test-%d", i), func(t *testing.T) {
+			t.Parallel()
+
+			rec := New(t)
+
+			for j := 0; j <= i; j++ {
+				serviceCall(rec.Context(), false)
+			}
+
+			require.Len(t, rec.SpansNamed("serviceCall"), i+1)
+			require.Len(t, rec.SpansNamed("dbCall"), i+1)
+		})
+	}
+}
+
+func TestRecorder_DisablesTracerOnCleanup(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		New(t)
+		require.NotNil(t, coretracer.DefaultTracer())
+	})
+
+	require.Nil(t, coretracer.DefaultTracer())
+}
+
+// recordingTB captures assertion failures instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder_ReportsFailures(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	rec := New(tb)
+
+	serviceCall(rec.Context(), false)
+
+	rec.Span("dbCall").
+		IsRoot().
+		HasParent("otherCall").
+		HasTag("svc", "myService").
+		HasStatusError().
+		HasException("db is down").
+		HasChildCount(2)
+
+	rec.Span("missingCall").HasTag("svc", "myService")
+	rec.NoSpan("serviceCall")
+
+	require.Len(t, tb.errors, 8)
+	require.Contains(t, tb.errors[0], `expected span "dbCall" to be a root span, its parent is "serviceCall"`)
+	require.Contains(t, tb.errors[1], `expected span "dbCall" to have parent "otherCall", got "serviceCall"`)
+	require.Contains(t, tb.errors[2], `expected span "dbCall" to have tag svc=myService, tag is missing`)
+	require.Contains(t, tb.errors[3], `expected span "dbCall" to have status Error, got Ok`)
+	require.Contains(t, tb.errors[4], `expected span "dbCall" to have exception "db is down"`)
+	require.Contains(t, tb.errors[5], `expected span "dbCall" to have 2 children, got 0`)
+	require.Contains(t, tb.errors[6], `expected span "missingCall" to be recorded`)
+	require.Contains(t, tb.errors[7], `expected no span "serviceCall" to be recorded, got 1`)
+}
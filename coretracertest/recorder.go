@@ -0,0 +1,103 @@
+package coretracertest
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+// Recorder gives access to the spans recorded within a single test.
+type Recorder struct {
+	t       testing.TB
+	traceID oteltracer.TraceID
+}
+
+// Context returns a context to pass into the code under test.
+func (r *Recorder) Context() context.Context {
+	return r.ContextFrom(context.Background())
+}
+
+// ContextFrom returns a child of the given context to pass into the code under test.
+// The context carries a synthetic remote parent, which makes spans started from it
+// part of the recorder's trace.
+func (r *Recorder) ContextFrom(ctx context.Context) context.Context {
+	return oteltracer.ContextWithRemoteSpanContext(ctx, r.parentSpanContext())
+}
+
+func (r *Recorder) parentSpanContext() oteltracer.SpanContext {
+	// derive the parent span ID from the trace ID, so it stays the same for every context
+	var spanID oteltracer.SpanID
+	copy(spanID[:], r.traceID[:len(spanID)])
+
+	return oteltracer.NewSpanContext(oteltracer.SpanContextConfig{
+		TraceID:    r.traceID,
+		SpanID:     spanID,
+		TraceFlags: oteltracer.FlagsSampled,
+		Remote:     true,
+	})
+}
+
+// Spans returns the ended spans recorded so far, ordered by start time.
+func (r *Recorder) Spans() []sdktrace.ReadOnlySpan {
+	store := currentStore()
+	if store == nil {
+		return nil
+	}
+
+	spans := store.get(r.traceID)
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime().Before(spans[j].StartTime())
+	})
+
+	return spans
+}
+
+// SpansNamed returns the ended spans with the given name, ordered by start time.
+func (r *Recorder) SpansNamed(name string) []sdktrace.ReadOnlySpan {
+	var named []sdktrace.ReadOnlySpan
+	for _, span := range r.Spans() {
+		if span.Name() == name {
+			named = append(named, span)
+		}
+	}
+
+	return named
+}
+
+// Span asserts that a span with the given name has ended and returns assertions for
+// the earliest one of them. Subsequent assertions are skipped if there is no such span.
+func (r *Recorder) Span(name string) *SpanAssert {
+	r.t.Helper()
+
+	spans := r.SpansNamed(name)
+	if len(spans) == 0 {
+		r.t.Errorf("coretracertest: expected span %q to be recorded, got spans: %v", name, spanNames(r.Spans()))
+		return &SpanAssert{rec: r}
+	}
+
+	return &SpanAssert{
+		rec:  r,
+		span: spans[0],
+	}
+}
+
+// NoSpan asserts that no span with the given name has ended.
+func (r *Recorder) NoSpan(name string) {
+	r.t.Helper()
+
+	if spans := r.SpansNamed(name); len(spans) > 0 {
+		r.t.Errorf("coretracertest: expected no span %q to be recorded, got %d", name, len(spans))
+	}
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+
+	return names
+}
@@ -0,0 +1,447 @@
+package coretracer
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables specific to coretracer, the rest follow the OpenTelemetry spec.
+const (
+	EnvStuckFunctionWatchdog = "CORETRACER_STUCK_FUNCTION_WATCHDOG"
+	EnvStuckFunctionTimeout  = "CORETRACER_STUCK_FUNCTION_TIMEOUT"
+	EnvQueueOverflowPolicy   = "CORETRACER_QUEUE_OVERFLOW_POLICY"
+)
+
+// ConfigFromEnv builds the config from the standard OTEL_* environment variables:
+//
+//   - OTEL_SDK_DISABLED
+//   - OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES
+//   - OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_HEADERS, OTEL_EXPORTER_OTLP_PROTOCOL,
+//     OTEL_EXPORTER_OTLP_INSECURE, OTEL_EXPORTER_OTLP_CERTIFICATE, OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE,
+//     OTEL_EXPORTER_OTLP_CLIENT_KEY, and their OTEL_EXPORTER_OTLP_TRACES_* variants, which take precedence
+//   - OTEL_TRACES_SAMPLER, OTEL_TRACES_SAMPLER_ARG
+//   - OTEL_BSP_MAX_QUEUE_SIZE, OTEL_BSP_MAX_EXPORT_BATCH_SIZE, OTEL_BSP_EXPORT_TIMEOUT, OTEL_BSP_SCHEDULE_DELAY
+//   - OTEL_PROPAGATORS
+//
+// as well as CORETRACER_STUCK_FUNCTION_WATCHDOG, CORETRACER_STUCK_FUNCTION_TIMEOUT
+// and CORETRACER_QUEUE_OVERFLOW_POLICY.
+// Values that fail to parse are reported in the error, all of them at once;
+// the returned config has the rest of the values and is usable anyway.
+func ConfigFromEnv() (*Config, error) {
+	return MergeEnv(nil)
+}
+
+// MergeEnv returns a copy of cfg, with empty fields filled from the environment, see ConfigFromEnv.
+// Values set in cfg take precedence, headers and resource attributes are merged key by key.
+// Boolean flags are the exception, as false can't be told from unset in cfg: the environment
+// wins when it sets them, e.g. OTEL_SDK_DISABLED=true turns Enabled off. Otherwise cfg keeps its value.
+func MergeEnv(cfg *Config) (*Config, error) {
+	envCfg, set, err := parseEnv()
+	if cfg == nil {
+		return envCfg, err
+	}
+
+	return mergeConfig(cfg, envCfg, set), err
+}
+
+// envSet tells which boolean fields the environment sets, their defaults don't override cfg.
+type envSet struct {
+	enabled               bool
+	collectorSecureSSL    bool
+	stuckFunctionWatchdog bool
+	// endpointScheme is set when CollectorSecureSSL follows the scheme of the endpoint,
+	// applied only along with the endpoint.
+	endpointScheme bool
+}
+
+func parseEnv() (*Config, envSet, error) {
+	p := new(envParser)
+	cfg := &Config{Enabled: true}
+
+	if disabled, ok := p.bool("OTEL_SDK_DISABLED"); ok {
+		cfg.Enabled = !disabled
+		p.set.enabled = true
+	}
+
+	if attributes, ok := p.keyValues("OTEL_RESOURCE_ATTRIBUTES"); ok {
+		cfg.ServiceName = attributes["service.name"]
+		cfg.ServiceVersion = attributes["service.version"]
+		cfg.EnvName = attributes["deployment.environment"]
+		cfg.ClusterID = attributes["deployment.cluster_id"]
+
+		for _, key := range []string{"service.name", "service.version", "deployment.environment", "deployment.cluster_id"} {
+			delete(attributes, key)
+		}
+
+		if len(attributes) > 0 {
+			cfg.ResourceAttributes = attributes
+		}
+	}
+
+	// takes precedence over the resource attribute, per spec
+	if serviceName, ok := p.string("OTEL_SERVICE_NAME"); ok {
+		cfg.ServiceName = serviceName
+	}
+
+	if protocol, ok := p.string("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"); ok {
+		switch protocol {
+		case CollectorProtocolGRPC, CollectorProtocolHTTPProtobuf, CollectorProtocolHTTPJSON:
+			cfg.CollectorProtocol = protocol
+		default:
+			p.fail("OTEL_EXPORTER_OTLP_PROTOCOL", fmt.Errorf("unknown protocol %q", protocol))
+		}
+	}
+
+	if insecure, ok := p.bool("OTEL_EXPORTER_OTLP_TRACES_INSECURE", "OTEL_EXPORTER_OTLP_INSECURE"); ok {
+		cfg.CollectorSecureSSL = !insecure
+		p.set.collectorSecureSSL = true
+	}
+
+	if endpoint, ok := p.string("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); ok {
+		p.endpoint(cfg, "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", endpoint, false)
+	} else if endpoint, ok := p.string("OTEL_EXPORTER_OTLP_ENDPOINT"); ok {
+		p.endpoint(cfg, "OTEL_EXPORTER_OTLP_ENDPOINT", endpoint, true)
+	}
+
+	if headers, ok := p.keyValues("OTEL_EXPORTER_OTLP_HEADERS"); ok {
+		cfg.CollectorHeaders = headers
+	}
+
+	if headers, ok := p.keyValues("OTEL_EXPORTER_OTLP_TRACES_HEADERS"); ok {
+		if cfg.CollectorHeaders == nil {
+			cfg.CollectorHeaders = headers
+		} else {
+			maps.Copy(cfg.CollectorHeaders, headers)
+		}
+	}
+
+	cfg.CollectorTLS.CAFile, _ = p.string("OTEL_EXPORTER_OTLP_TRACES_CERTIFICATE", "OTEL_EXPORTER_OTLP_CERTIFICATE")
+	cfg.CollectorTLS.CertFile, _ = p.string("OTEL_EXPORTER_OTLP_TRACES_CLIENT_CERTIFICATE", "OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE")
+	cfg.CollectorTLS.KeyFile, _ = p.string("OTEL_EXPORTER_OTLP_TRACES_CLIENT_KEY", "OTEL_EXPORTER_OTLP_CLIENT_KEY")
+
+	if sampler, ok := p.string("OTEL_TRACES_SAMPLER"); ok {
+		switch sampler {
+		case SamplerAlwaysOn, SamplerAlwaysOff, SamplerParentBasedAlwaysOn, SamplerParentBasedAlwaysOff:
+			cfg.Sampling.Sampler = sampler
+		case SamplerTraceIDRatio, SamplerParentBasedTraceIDRatio:
+			cfg.Sampling.Sampler = sampler
+			cfg.Sampling.Ratio = 1
+		default:
+			p.fail("OTEL_TRACES_SAMPLER", fmt.Errorf("unknown sampler %q", sampler))
+		}
+	}
+
+	if arg, ok := p.string("OTEL_TRACES_SAMPLER_ARG"); ok {
+		ratio, err := strconv.ParseFloat(arg, 64)
+
+		switch {
+		case err != nil:
+			p.fail("OTEL_TRACES_SAMPLER_ARG", err)
+		case ratio < 0 || ratio > 1:
+			p.fail("OTEL_TRACES_SAMPLER_ARG", fmt.Errorf("ratio %v is out of [0, 1] range", ratio))
+		case cfg.Sampling.Sampler == SamplerTraceIDRatio || cfg.Sampling.Sampler == SamplerParentBasedTraceIDRatio:
+			cfg.Sampling.Ratio = ratio
+		}
+	}
+
+	cfg.Queue.Size = p.positiveInt("OTEL_BSP_MAX_QUEUE_SIZE")
+	cfg.Queue.BatchSize = p.positiveInt("OTEL_BSP_MAX_EXPORT_BATCH_SIZE")
+	cfg.Queue.ExportTimeout = time.Duration(p.positiveInt("OTEL_BSP_EXPORT_TIMEOUT")) * time.Millisecond
+	cfg.Queue.ScheduleDelay = time.Duration(p.positiveInt("OTEL_BSP_SCHEDULE_DELAY")) * time.Millisecond
+
+	if policy, ok := p.string(EnvQueueOverflowPolicy); ok {
+		switch policy {
+		case OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+			cfg.Queue.OverflowPolicy = policy
+		default:
+			p.fail(EnvQueueOverflowPolicy, fmt.Errorf("unknown policy %q", policy))
+		}
+	}
+
+	if propagators, ok := p.list("OTEL_PROPAGATORS"); ok {
+		for _, propagator := range propagators {
+			switch propagator {
+			case PropagatorTraceContext, PropagatorBaggage, PropagatorNone:
+				cfg.Propagators = append(cfg.Propagators, propagator)
+			default:
+				p.fail("OTEL_PROPAGATORS", fmt.Errorf("unsupported propagator %q", propagator))
+			}
+		}
+	}
+
+	if watchdog, ok := p.bool(EnvStuckFunctionWatchdog); ok {
+		cfg.StuckFunctionWatchdog = watchdog
+		p.set.stuckFunctionWatchdog = true
+	}
+
+	if timeout, ok := p.string(EnvStuckFunctionTimeout); ok {
+		d, err := time.ParseDuration(timeout)
+
+		switch {
+		case err != nil:
+			p.fail(EnvStuckFunctionTimeout, err)
+		case d < time.Second:
+			p.fail(EnvStuckFunctionTimeout, fmt.Errorf("timeout %v is shorter than 1s", d))
+		default:
+			cfg.StuckFunctionTimeout = d
+		}
+	}
+
+	return cfg, p.set, errors.Join(p.errs...)
+}
+
+// mergeConfig fills empty fields of cfg from the other config, into a copy.
+// Boolean fields are taken from the other config when it sets them.
+func mergeConfig(cfg, other *Config, set envSet) *Config {
+	merged := *cfg
+
+	if set.enabled {
+		merged.Enabled = other.Enabled
+	}
+
+	// the scheme belongs to the endpoint, that doesn't apply over an explicit DSN
+	if set.collectorSecureSSL || set.endpointScheme && len(cfg.CollectorDSN) == 0 {
+		merged.CollectorSecureSSL = other.CollectorSecureSSL
+	}
+
+	if set.stuckFunctionWatchdog {
+		merged.StuckFunctionWatchdog = other.StuckFunctionWatchdog
+	}
+
+	for _, field := range []struct{ dst, src *string }{
+		{&merged.EnvName, &other.EnvName},
+		{&merged.ServiceName, &other.ServiceName},
+		{&merged.ServiceVersion, &other.ServiceVersion},
+		{&merged.ClusterID, &other.ClusterID},
+		{&merged.CollectorDSN, &other.CollectorDSN},
+		{&merged.CollectorProtocol, &other.CollectorProtocol},
+		{&merged.CollectorURLPath, &other.CollectorURLPath},
+		{&merged.CollectorTLS.CAFile, &other.CollectorTLS.CAFile},
+		{&merged.CollectorTLS.CertFile, &other.CollectorTLS.CertFile},
+		{&merged.CollectorTLS.KeyFile, &other.CollectorTLS.KeyFile},
+	} {
+		if len(*field.dst) == 0 {
+			*field.dst = *field.src
+		}
+	}
+
+	merged.CollectorHeaders = mergeMaps(cfg.CollectorHeaders, other.CollectorHeaders)
+	merged.ResourceAttributes = mergeMaps(cfg.ResourceAttributes, other.ResourceAttributes)
+
+	if len(merged.Sampling.Sampler) == 0 {
+		merged.Sampling.Sampler = other.Sampling.Sampler
+		merged.Sampling.Ratio = other.Sampling.Ratio
+	}
+
+	for _, field := range []struct{ dst, src *int }{
+		{&merged.Queue.Size, &other.Queue.Size},
+		{&merged.Queue.BatchSize, &other.Queue.BatchSize},
+	} {
+		if *field.dst == 0 {
+			*field.dst = *field.src
+		}
+	}
+
+	for _, field := range []struct{ dst, src *time.Duration }{
+		{&merged.StuckFunctionTimeout, &other.StuckFunctionTimeout},
+		{&merged.Queue.ExportTimeout, &other.Queue.ExportTimeout},
+		{&merged.Queue.ScheduleDelay, &other.Queue.ScheduleDelay},
+		{&merged.Queue.BlockTimeout, &other.Queue.BlockTimeout},
+	} {
+		if *field.dst == 0 {
+			*field.dst = *field.src
+		}
+	}
+
+	if len(merged.Propagators) == 0 {
+		merged.Propagators = other.Propagators
+	}
+
+	if len(merged.Queue.OverflowPolicy) == 0 {
+		merged.Queue.OverflowPolicy = other.Queue.OverflowPolicy
+	}
+
+	if merged.Logger == nil {
+		merged.Logger = other.Logger
+	}
+
+	return &merged
+}
+
+// mergeMaps returns a new map with the entries of both, the first one wins.
+func mergeMaps(m, other map[string]string) map[string]string {
+	if len(m) == 0 && len(other) == 0 {
+		return m
+	}
+
+	merged := make(map[string]string, len(m)+len(other))
+	maps.Copy(merged, other)
+	maps.Copy(merged, m)
+
+	return merged
+}
+
+// envParser reads environment variables, collecting parse errors on the way.
+type envParser struct {
+	errs []error
+	set  envSet
+}
+
+func (p *envParser) fail(name string, err error) {
+	p.errs = append(p.errs, fmt.Errorf("invalid %s: %w", name, err))
+}
+
+// string returns the value of the first non-empty variable.
+func (p *envParser) string(names ...string) (string, bool) {
+	for _, name := range names {
+		if value := strings.TrimSpace(os.Getenv(name)); len(value) > 0 {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *envParser) bool(names ...string) (bool, bool) {
+	for _, name := range names {
+		value, ok := p.string(name)
+		if !ok {
+			continue
+		}
+
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			p.fail(name, err)
+			return false, false
+		}
+
+		return b, true
+	}
+
+	return false, false
+}
+
+// positiveInt returns zero when the variable is not set.
+func (p *envParser) positiveInt(name string) int {
+	value, ok := p.string(name)
+	if !ok {
+		return 0
+	}
+
+	n, err := strconv.Atoi(value)
+	if err == nil && n <= 0 {
+		err = fmt.Errorf("%d is not positive", n)
+	}
+
+	if err != nil {
+		p.fail(name, err)
+		return 0
+	}
+
+	return n
+}
+
+// list parses the comma-separated list, skipping empty items.
+func (p *envParser) list(name string) ([]string, bool) {
+	value, ok := p.string(name)
+	if !ok {
+		return nil, false
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+
+	return items, len(items) > 0
+}
+
+// keyValues parses the "key1=value1,key2=value2" format with URL-encoded values,
+// shared by OTEL_RESOURCE_ATTRIBUTES and OTEL_EXPORTER_OTLP_HEADERS.
+func (p *envParser) keyValues(name string) (map[string]string, bool) {
+	value, ok := p.string(name)
+	if !ok {
+		return nil, false
+	}
+
+	out := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		if len(strings.TrimSpace(pair)) == 0 {
+			continue
+		}
+
+		k, v, found := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+
+		if !found || len(k) == 0 {
+			p.fail(name, fmt.Errorf("malformed pair %q", pair))
+			continue
+		}
+
+		decoded, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			p.fail(name, fmt.Errorf("malformed value of %q: %w", k, err))
+			continue
+		}
+
+		out[k] = decoded
+	}
+
+	return out, len(out) > 0
+}
+
+// endpoint parses the collector URL into the DSN, the URL path and the TLS toggle.
+// For the generic variable, the path is a base path the signal path is appended to.
+func (p *envParser) endpoint(cfg *Config, name, endpoint string, isBase bool) {
+	if !strings.Contains(endpoint, "://") {
+		// tolerate the bare host:port form used by gRPC exporters
+		cfg.CollectorDSN = endpoint
+		return
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		p.fail(name, err)
+		return
+	}
+
+	switch u.Scheme {
+	case "https":
+		cfg.CollectorSecureSSL = true
+	case "http":
+		cfg.CollectorSecureSSL = false
+	default:
+		p.fail(name, fmt.Errorf("unsupported scheme %q", u.Scheme))
+		return
+	}
+
+	if len(u.Host) == 0 {
+		p.fail(name, errors.New("missing host"))
+		return
+	}
+
+	p.set.endpointScheme = true
+
+	cfg.CollectorDSN = u.Host
+
+	urlPath := strings.TrimSuffix(u.Path, "/")
+	if isBase {
+		if len(urlPath) == 0 {
+			// exporters default to /v1/traces
+			return
+		}
+
+		urlPath += "/v1/traces"
+	}
+
+	cfg.CollectorURLPath = urlPath
+}
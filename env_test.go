@@ -0,0 +1,255 @@
+package coretracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var envVariables = []string{
+	"OTEL_SDK_DISABLED",
+	"OTEL_SERVICE_NAME",
+	"OTEL_RESOURCE_ATTRIBUTES",
+	"OTEL_EXPORTER_OTLP_ENDPOINT",
+	"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+	"OTEL_EXPORTER_OTLP_HEADERS",
+	"OTEL_EXPORTER_OTLP_TRACES_HEADERS",
+	"OTEL_EXPORTER_OTLP_PROTOCOL",
+	"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL",
+	"OTEL_EXPORTER_OTLP_INSECURE",
+	"OTEL_EXPORTER_OTLP_TRACES_INSECURE",
+	"OTEL_EXPORTER_OTLP_CERTIFICATE",
+	"OTEL_EXPORTER_OTLP_TRACES_CERTIFICATE",
+	"OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE",
+	"OTEL_EXPORTER_OTLP_TRACES_CLIENT_CERTIFICATE",
+	"OTEL_EXPORTER_OTLP_CLIENT_KEY",
+	"OTEL_EXPORTER_OTLP_TRACES_CLIENT_KEY",
+	"OTEL_TRACES_SAMPLER",
+	"OTEL_TRACES_SAMPLER_ARG",
+	"OTEL_BSP_MAX_QUEUE_SIZE",
+	"OTEL_BSP_MAX_EXPORT_BATCH_SIZE",
+	"OTEL_BSP_EXPORT_TIMEOUT",
+	"OTEL_BSP_SCHEDULE_DELAY",
+	"OTEL_PROPAGATORS",
+	EnvQueueOverflowPolicy,
+	EnvStuckFunctionWatchdog,
+	EnvStuckFunctionTimeout,
+}
+
+func setEnv(t *testing.T, env map[string]string) {
+	for _, name := range envVariables {
+		t.Setenv(name, "")
+	}
+
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	setEnv(t, map[string]string{
+		"OTEL_SERVICE_NAME":                  "api",
+		"OTEL_RESOURCE_ATTRIBUTES":           "service.name=ignored,service.version=1.2.3,deployment.environment=prod,k8s.namespace.name=core%20services",
+		"OTEL_EXPORTER_OTLP_ENDPOINT":        "https://collector.internal:4318/otlp",
+		"OTEL_EXPORTER_OTLP_HEADERS":         "x-api-key=secret,x-tenant=a",
+		"OTEL_EXPORTER_OTLP_TRACES_HEADERS":  "x-tenant=b",
+		"OTEL_EXPORTER_OTLP_PROTOCOL":        "grpc",
+		"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL": "http/json",
+		"OTEL_EXPORTER_OTLP_CERTIFICATE":     "/etc/tls/ca.pem",
+		"OTEL_TRACES_SAMPLER":                "parentbased_traceidratio",
+		"OTEL_TRACES_SAMPLER_ARG":            "0.25",
+		"OTEL_BSP_MAX_QUEUE_SIZE":            "4096",
+		"OTEL_BSP_EXPORT_TIMEOUT":            "10000",
+		"OTEL_PROPAGATORS":                   "tracecontext, baggage",
+		EnvQueueOverflowPolicy:               "drop_oldest",
+		EnvStuckFunctionWatchdog:             "true",
+		EnvStuckFunctionTimeout:              "30s",
+	})
+
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+
+	require.True(t, cfg.Enabled)
+	require.Equal(t, "api", cfg.ServiceName, "Expected OTEL_SERVICE_NAME to win over the resource attribute")
+	require.Equal(t, "1.2.3", cfg.ServiceVersion)
+	require.Equal(t, "prod", cfg.EnvName)
+	require.Equal(t, map[string]string{"k8s.namespace.name": "core services"}, cfg.ResourceAttributes)
+
+	require.Equal(t, "collector.internal:4318", cfg.CollectorDSN)
+	require.Equal(t, "/otlp/v1/traces", cfg.CollectorURLPath)
+	require.True(t, cfg.CollectorSecureSSL)
+	require.Equal(t, map[string]string{"x-api-key": "secret", "x-tenant": "b"}, cfg.CollectorHeaders)
+	require.Equal(t, CollectorProtocolHTTPJSON, cfg.CollectorProtocol)
+	require.Equal(t, "/etc/tls/ca.pem", cfg.CollectorTLS.CAFile)
+
+	require.Equal(t, SamplingConfig{Sampler: SamplerParentBasedTraceIDRatio, Ratio: 0.25}, cfg.Sampling)
+	require.Equal(t, QueueConfig{
+		Size:           4096,
+		ExportTimeout:  10 * time.Second,
+		OverflowPolicy: OverflowDropOldest,
+	}, cfg.Queue)
+	require.Equal(t, []string{PropagatorTraceContext, PropagatorBaggage}, cfg.Propagators)
+
+	require.True(t, cfg.StuckFunctionWatchdog)
+	require.Equal(t, 30*time.Second, cfg.StuckFunctionTimeout)
+}
+
+func TestConfigFromEnv_Endpoint(t *testing.T) {
+	testCases := []struct {
+		env             map[string]string
+		expectedDSN     string
+		expectedURLPath string
+		expectedSecure  bool
+	}{{
+		env:         map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4317"},
+		expectedDSN: "localhost:4317",
+	}, {
+		env: map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://localhost:4317",
+			"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "https://traces.internal/custom/path",
+		},
+		expectedDSN:     "traces.internal",
+		expectedURLPath: "/custom/path",
+		expectedSecure:  true,
+	}, {
+		env: map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT": "collector:4317",
+			"OTEL_EXPORTER_OTLP_INSECURE": "false",
+		},
+		expectedDSN:    "collector:4317",
+		expectedSecure: true,
+	}}
+
+	for _, tc := range testCases {
+		setEnv(t, tc.env)
+
+		cfg, err := ConfigFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, tc.expectedDSN, cfg.CollectorDSN)
+		require.Equal(t, tc.expectedURLPath, cfg.CollectorURLPath)
+		require.Equal(t, tc.expectedSecure, cfg.CollectorSecureSSL)
+	}
+}
+
+func TestConfigFromEnv_ReportsErrors(t *testing.T) {
+	setEnv(t, map[string]string{
+		"OTEL_SDK_DISABLED":           "nope",
+		"OTEL_SERVICE_NAME":           "api",
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "ftp://collector",
+		"OTEL_EXPORTER_OTLP_HEADERS":  "x-api-key",
+		"OTEL_EXPORTER_OTLP_PROTOCOL": "thrift",
+		"OTEL_TRACES_SAMPLER":         "sometimes",
+		"OTEL_TRACES_SAMPLER_ARG":     "2",
+		"OTEL_BSP_MAX_QUEUE_SIZE":     "-1",
+		"OTEL_PROPAGATORS":            "tracecontext,b3",
+		EnvQueueOverflowPolicy:        "panic",
+		EnvStuckFunctionTimeout:       "5 minutes",
+	})
+
+	cfg, err := ConfigFromEnv()
+	require.Error(t, err)
+
+	for _, name := range []string{
+		"OTEL_SDK_DISABLED",
+		"OTEL_EXPORTER_OTLP_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_HEADERS",
+		"OTEL_EXPORTER_OTLP_PROTOCOL",
+		"OTEL_TRACES_SAMPLER",
+		"OTEL_TRACES_SAMPLER_ARG",
+		"OTEL_BSP_MAX_QUEUE_SIZE",
+		"OTEL_PROPAGATORS",
+		EnvQueueOverflowPolicy,
+		EnvStuckFunctionTimeout,
+	} {
+		require.ErrorContains(t, err, "invalid "+name)
+	}
+
+	// valid values are kept, defaults are not applied to the broken ones
+	require.Equal(t, "api", cfg.ServiceName)
+	require.Empty(t, cfg.Sampling.Sampler)
+	require.Zero(t, cfg.StuckFunctionTimeout)
+}
+
+func TestMergeEnv(t *testing.T) {
+	setEnv(t, map[string]string{
+		"OTEL_SERVICE_NAME":           "from-env",
+		"OTEL_RESOURCE_ATTRIBUTES":    "service.version=1.0.0,team=core",
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "collector:4317",
+		"OTEL_EXPORTER_OTLP_HEADERS":  "x-api-key=env,x-tenant=env",
+		"OTEL_TRACES_SAMPLER":         "always_off",
+	})
+
+	explicit := &Config{
+		ServiceName:        "explicit",
+		ResourceAttributes: map[string]string{"team": "infra"},
+		CollectorHeaders:   map[string]string{"x-api-key": "explicit"},
+		Sampling:           SamplingConfig{Sampler: SamplerAlwaysOn},
+	}
+
+	cfg, err := MergeEnv(explicit)
+	require.NoError(t, err)
+
+	require.Equal(t, "explicit", cfg.ServiceName)
+	require.Equal(t, "1.0.0", cfg.ServiceVersion)
+	require.Equal(t, "collector:4317", cfg.CollectorDSN)
+	require.Equal(t, map[string]string{"team": "infra"}, cfg.ResourceAttributes)
+	require.Equal(t, map[string]string{"x-api-key": "explicit", "x-tenant": "env"}, cfg.CollectorHeaders)
+	require.Equal(t, SamplerAlwaysOn, cfg.Sampling.Sampler)
+
+	require.Equal(t, map[string]string{"x-api-key": "explicit"}, explicit.CollectorHeaders, "Expected explicit config to stay intact")
+}
+
+func TestMergeEnv_Flags(t *testing.T) {
+	explicit := &Config{
+		Enabled:               true,
+		CollectorDSN:          "explicit:4317",
+		CollectorSecureSSL:    true,
+		StuckFunctionWatchdog: true,
+	}
+
+	t.Run("unset", func(t *testing.T) {
+		setEnv(t, map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318",
+		})
+
+		cfg, err := MergeEnv(explicit)
+		require.NoError(t, err)
+		require.True(t, cfg.Enabled)
+		require.True(t, cfg.CollectorSecureSSL, "Expected the scheme of the unused endpoint to be ignored")
+		require.True(t, cfg.StuckFunctionWatchdog)
+
+		cfg, err = MergeEnv(&Config{})
+		require.NoError(t, err)
+		require.False(t, cfg.Enabled, "Expected the environment default not to override cfg")
+		require.False(t, cfg.CollectorSecureSSL)
+	})
+
+	t.Run("turned off", func(t *testing.T) {
+		setEnv(t, map[string]string{
+			"OTEL_SDK_DISABLED":           "true",
+			"OTEL_EXPORTER_OTLP_INSECURE": "true",
+			EnvStuckFunctionWatchdog:      "false",
+		})
+
+		cfg, err := MergeEnv(explicit)
+		require.NoError(t, err)
+		require.False(t, cfg.Enabled)
+		require.False(t, cfg.CollectorSecureSSL)
+		require.False(t, cfg.StuckFunctionWatchdog)
+	})
+
+	t.Run("turned on", func(t *testing.T) {
+		setEnv(t, map[string]string{
+			"OTEL_SDK_DISABLED":           "false",
+			"OTEL_EXPORTER_OTLP_ENDPOINT": "https://collector:4318",
+			EnvStuckFunctionWatchdog:      "true",
+		})
+
+		cfg, err := MergeEnv(&Config{})
+		require.NoError(t, err)
+		require.True(t, cfg.Enabled)
+		require.True(t, cfg.CollectorSecureSSL)
+		require.True(t, cfg.StuckFunctionWatchdog)
+	})
+}
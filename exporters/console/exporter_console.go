@@ -0,0 +1,330 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltracer "go.opentelemetry.io/otel/trace"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+// maxPendingTraces limits the amount of traces waiting for their root span,
+// the oldest incomplete trace is printed as-is once the limit is reached.
+const maxPendingTraces = 1024
+
+const (
+	colorReset = "\033[0m"
+	colorBold  = "\033[1m"
+	colorDim   = "\033[2m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+)
+
+// Options configures the console exporter.
+type Options struct {
+	// Writer receives the printed traces, defaults to os.Stdout.
+	Writer io.Writer
+	// Colors enables ANSI colours in the output.
+	Colors bool
+}
+
+// InitExporter prints finished traces to stdout, without colours.
+func InitExporter(cfg *coretracer.Config) coretracer.ExporterShutdownFn {
+	return NewInitExporter(Options{})(cfg)
+}
+
+// NewInitExporter returns an init function for coretracer.Enable that prints
+// finished traces as indented trees, according to the options.
+func NewInitExporter(opts Options) func(cfg *coretracer.Config) coretracer.ExporterShutdownFn {
+	return func(cfg *coretracer.Config) coretracer.ExporterShutdownFn {
+		shutdownFn, err := coretracer.InitTraceProvider(cfg, NewExporter(opts))
+		if err != nil {
+			slog.Warn("coretracer: console exporter: failed to init trace provider", "error", err)
+			return emptyShutdownFn()
+		}
+
+		return shutdownFn
+	}
+}
+
+// NewSpanExporterFn returns a console span exporter constructor, see coretracer.SpanExporterFn.
+func NewSpanExporterFn(opts Options) coretracer.SpanExporterFn {
+	return func(cfg *coretracer.Config) (sdktrace.SpanExporter, error) {
+		return NewExporter(opts), nil
+	}
+}
+
+var _ sdktrace.SpanExporter = (*Exporter)(nil)
+
+// Exporter is a span exporter that buffers spans until the local root span
+// of their trace ends, then prints the whole trace as a tree.
+type Exporter struct {
+	mux     sync.Mutex
+	w       io.Writer
+	colors  bool
+	pending map[oteltracer.TraceID][]sdktrace.ReadOnlySpan
+	order   []oteltracer.TraceID
+}
+
+// NewExporter creates a console exporter according to the options.
+func NewExporter(opts Options) *Exporter {
+	w := opts.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+
+	return &Exporter{
+		w:       w,
+		colors:  opts.Colors,
+		pending: make(map[oteltracer.TraceID][]sdktrace.ReadOnlySpan),
+	}
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e *Exporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	for _, span := range spans {
+		traceID := span.SpanContext().TraceID()
+
+		if _, ok := e.pending[traceID]; !ok {
+			e.order = append(e.order, traceID)
+		}
+
+		e.pending[traceID] = append(e.pending[traceID], span)
+
+		if isLocalRoot(span) {
+			if err := e.flushTrace(traceID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for len(e.pending) > maxPendingTraces {
+		if err := e.flushTrace(e.order[0]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter. Prints traces that never got their root span ended.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	for len(e.order) > 0 {
+		if err := e.flushTrace(e.order[0]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Exporter) flushTrace(traceID oteltracer.TraceID) error {
+	spans := e.pending[traceID]
+	delete(e.pending, traceID)
+
+	for i, id := range e.order {
+		if id == traceID {
+			e.order = append(e.order[:i], e.order[i+1:]...)
+			break
+		}
+	}
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	_, err := e.w.Write(e.formatTrace(traceID, spans))
+	return err
+}
+
+func isLocalRoot(span sdktrace.ReadOnlySpan) bool {
+	parent := span.Parent()
+	return !parent.IsValid() || parent.IsRemote()
+}
+
+func (e *Exporter) formatTrace(traceID oteltracer.TraceID, spans []sdktrace.ReadOnlySpan) []byte {
+	byID := make(map[oteltracer.SpanID]bool, len(spans))
+	for _, span := range spans {
+		byID[span.SpanContext().SpanID()] = true
+	}
+
+	children := make(map[oteltracer.SpanID][]sdktrace.ReadOnlySpan, len(spans))
+	var roots []sdktrace.ReadOnlySpan
+
+	for _, span := range spans {
+		parentID := span.Parent().SpanID()
+		if span.Parent().IsValid() && byID[parentID] {
+			children[parentID] = append(children[parentID], span)
+			continue
+		}
+
+		// spans of incomplete traces end up as roots too
+		roots = append(roots, span)
+	}
+
+	sortByStartTime(roots)
+	for _, list := range children {
+		sortByStartTime(list)
+	}
+
+	buf := new(bytes.Buffer)
+
+	header := fmt.Sprintf("trace %s", traceID)
+	if svc, ok := spans[0].Resource().Set().Value("service.name"); ok {
+		header += " service=" + svc.Emit()
+	}
+	fmt.Fprintf(buf, "%s (%d spans)\n", e.paint(colorBold, header), len(spans))
+
+	for i, root := range roots {
+		e.formatSpan(buf, root, children, "", i == len(roots)-1)
+	}
+
+	return buf.Bytes()
+}
+
+func (e *Exporter) formatSpan(
+	buf *bytes.Buffer,
+	span sdktrace.ReadOnlySpan,
+	children map[oteltracer.SpanID][]sdktrace.ReadOnlySpan,
+	prefix string,
+	isLast bool,
+) {
+	connector, childPrefix := "├─ ", prefix+"│  "
+	if isLast {
+		connector, childPrefix = "└─ ", prefix+"   "
+	}
+
+	line := []string{
+		e.paint(colorBold, span.Name()),
+		e.paint(colorCyan, formatDuration(span.EndTime().Sub(span.StartTime()))),
+		e.formatStatus(span.Status()),
+	}
+
+	line = append(line, e.formatAttributes(span.Attributes())...)
+	fmt.Fprintf(buf, "%s%s%s\n", prefix, connector, strings.Join(line, " "))
+
+	spanChildren := children[span.SpanContext().SpanID()]
+
+	eventPrefix := childPrefix
+	if len(spanChildren) == 0 {
+		// no tree branches below, keep events aligned with the span name
+		eventPrefix = prefix + "   "
+	}
+
+	for _, event := range span.Events() {
+		e.formatEvent(buf, event, eventPrefix)
+	}
+
+	for i, child := range spanChildren {
+		e.formatSpan(buf, child, children, childPrefix, i == len(spanChildren)-1)
+	}
+}
+
+func (e *Exporter) formatEvent(buf *bytes.Buffer, event sdktrace.Event, prefix string) {
+	if event.Name != "exception" {
+		line := append([]string{"• " + event.Name}, e.formatAttributes(event.Attributes)...)
+		fmt.Fprintf(buf, "%s%s\n", prefix, e.paint(colorDim, strings.Join(line, " ")))
+		return
+	}
+
+	var errType, errMessage, stackTrace string
+	otherAttributes := make([]otelattribute.KeyValue, 0, len(event.Attributes))
+
+	for _, attr := range event.Attributes {
+		switch attr.Key {
+		case "exception.type":
+			errType = attr.Value.Emit()
+		case "exception.message":
+			errMessage = attr.Value.Emit()
+		case "exception.stacktrace":
+			stackTrace = attr.Value.Emit()
+		default:
+			otherAttributes = append(otherAttributes, attr)
+		}
+	}
+
+	line := append([]string{"! " + errType + ": " + errMessage}, e.formatAttributes(otherAttributes)...)
+	fmt.Fprintf(buf, "%s%s\n", prefix, e.paint(colorRed, strings.Join(line, " ")))
+
+	if len(stackTrace) == 0 {
+		return
+	}
+
+	for _, stackLine := range strings.Split(strings.TrimRight(stackTrace, "\n"), "\n") {
+		fmt.Fprintf(buf, "%s    %s\n", prefix, e.paint(colorDim, stackLine))
+	}
+}
+
+func (e *Exporter) formatStatus(status sdktrace.Status) string {
+	switch status.Code {
+	case otelcodes.Ok:
+		return e.paint(colorGreen, "OK")
+	case otelcodes.Error:
+		if len(status.Description) > 0 {
+			return e.paint(colorRed, "ERROR("+status.Description+")")
+		}
+
+		return e.paint(colorRed, "ERROR")
+	default:
+		return e.paint(colorDim, "UNSET")
+	}
+}
+
+func (e *Exporter) formatAttributes(attributes []otelattribute.KeyValue) []string {
+	out := make([]string, 0, len(attributes))
+	for _, attr := range attributes {
+		out = append(out, e.paint(colorDim, string(attr.Key)+"=")+attr.Value.Emit())
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+func (e *Exporter) paint(color, s string) string {
+	if !e.colors {
+		return s
+	}
+
+	return color + s + colorReset
+}
+
+func sortByStartTime(spans []sdktrace.ReadOnlySpan) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime().Before(spans[j].StartTime())
+	})
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	default:
+		return d.String()
+	}
+}
+
+func emptyShutdownFn() func(ctx context.Context) error {
+	return func(ctx context.Context) error { return nil }
+}
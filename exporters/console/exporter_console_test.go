@@ -0,0 +1,115 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+// syncBuffer guards the buffer, since the exporter writes from the batcher goroutine.
+type syncBuffer struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.String()
+}
+
+func parentFunc(ctx context.Context) {
+	defer coretracer.TraceWithName(&ctx, "parent", coretracer.NewTag("svc", "console"))()
+
+	okFunc(ctx)
+	failingFunc(ctx)
+}
+
+func okFunc(ctx context.Context) {
+	defer coretracer.TraceWithName(&ctx, "ok-child")()
+}
+
+func failingFunc(ctx context.Context) {
+	defer coretracer.TraceWithName(&ctx, "failing-child")()
+
+	coretracer.TraceError(ctx, errors.New("boom"))
+}
+
+func TestExporter_PrintsTraceTree(t *testing.T) {
+	out := new(syncBuffer)
+
+	coretracer.Enable(&coretracer.Config{
+		ServiceName: "console-test",
+	}, NewInitExporter(Options{Writer: out}))
+
+	parentFunc(context.Background())
+	coretracer.Close()
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	require.Greater(t, len(lines), 4, "Expected a trace tree, got:\n%s", out.String())
+
+	require.True(t, strings.HasPrefix(lines[0], "trace "), "Expected trace header, got %q", lines[0])
+	require.Contains(t, lines[0], "service=console-test")
+	require.Contains(t, lines[0], "(3 spans)")
+
+	require.True(t, strings.HasPrefix(lines[1], "└─ parent "), "Expected root span, got %q", lines[1])
+	require.Contains(t, lines[1], " OK")
+	require.Contains(t, lines[1], "svc=console")
+
+	require.True(t, strings.HasPrefix(lines[2], "   ├─ ok-child "), "Expected first child, got %q", lines[2])
+	require.True(t, strings.HasPrefix(lines[3], "   └─ failing-child "), "Expected second child, got %q", lines[3])
+	require.Contains(t, lines[3], "ERROR(boom)")
+	require.Contains(t, lines[4], "! *errors.errorString: boom")
+
+	// stack trace of the recorded error follows the exception line
+	require.Contains(t, strings.Join(lines[5:], "\n"), "exporters/console.failingFunc")
+	require.NotContains(t, out.String(), colorReset, "Expected no colours by default")
+}
+
+func TestExporter_Colors(t *testing.T) {
+	out := new(syncBuffer)
+
+	coretracer.Enable(&coretracer.Config{
+		ServiceName: "console-test",
+	}, NewInitExporter(Options{Writer: out, Colors: true}))
+
+	parentFunc(context.Background())
+	coretracer.Close()
+
+	require.Contains(t, out.String(), colorRed+"ERROR(boom)"+colorReset)
+	require.Contains(t, out.String(), colorGreen+"OK"+colorReset)
+}
+
+func TestExporter_FlushesIncompleteTracesOnShutdown(t *testing.T) {
+	out := new(syncBuffer)
+
+	coretracer.Enable(&coretracer.Config{
+		ServiceName: "console-test",
+	}, NewInitExporter(Options{Writer: out}))
+
+	ctx := context.Background()
+	endFn := coretracer.TraceWithName(&ctx, "never-ending-parent")
+	coretracer.TraceWithName(&ctx, "orphan-child")()
+
+	coretracer.Close()
+	endFn()
+
+	require.Contains(t, out.String(), "(1 spans)")
+	require.Contains(t, out.String(), "└─ orphan-child ")
+	require.NotContains(t, out.String(), "never-ending-parent")
+}
@@ -0,0 +1,347 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltracer "go.opentelemetry.io/otel/trace"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+const (
+	defaultAgentDSN = "localhost:8126"
+	tracesPath      = "/v0.4/traces"
+	defaultTimeout  = 10 * time.Second
+)
+
+// InitExporter sends spans right into the Datadog trace agent, using the v0.4 msgpack API.
+// The agent address is taken from cfg.CollectorDSN, defaults to localhost:8126.
+func InitExporter(cfg *coretracer.Config) coretracer.ExporterShutdownFn {
+	return coretracer.InitMultiExporter(NewSpanExporter)(cfg)
+}
+
+// NewSpanExporter creates a Datadog span exporter, see coretracer.SpanExporterFn.
+func NewSpanExporter(cfg *coretracer.Config) (sdktrace.SpanExporter, error) {
+	return NewExporter(cfg), nil
+}
+
+var _ sdktrace.SpanExporter = (*Exporter)(nil)
+
+// errShutdown is returned for spans exported after Shutdown, so they are counted as failed.
+var errShutdown = errors.New("datadog exporter is shut down")
+
+// Exporter converts finished spans into Datadog agent traces.
+type Exporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	envName     string
+	version     string
+	httpClient  *http.Client
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// NewExporter creates a Datadog exporter for the agent configured in cfg.
+func NewExporter(cfg *coretracer.Config) *Exporter {
+	dsn := cfg.CollectorDSN
+	if len(dsn) == 0 {
+		dsn = defaultAgentDSN
+	}
+
+	scheme := "http"
+	if cfg.CollectorSecureSSL {
+		scheme = "https"
+	}
+
+	return &Exporter{
+		url:         scheme + "://" + dsn + tracesPath,
+		headers:     cfg.CollectorHeaders,
+		serviceName: cfg.ServiceName,
+		envName:     cfg.EnvName,
+		version:     cfg.ServiceVersion,
+		httpClient:  &http.Client{Timeout: defaultTimeout},
+		stopped:     make(chan struct{}),
+	}
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e *Exporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	select {
+	case <-e.stopped:
+		return errShutdown
+	default:
+	}
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	traces := groupByTrace(spans)
+	payload := msgp.AppendArrayHeader(nil, uint32(len(traces)))
+
+	for _, trace := range traces {
+		payload = msgp.AppendArrayHeader(payload, uint32(len(trace)))
+
+		for _, span := range trace {
+			payload = e.appendSpan(payload, span)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, e.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("X-Datadog-Trace-Count", strconv.Itoa(len(traces)))
+	req.Header.Set("Datadog-Meta-Lang", "go")
+	req.Header.Set("Datadog-Meta-Tracer-Version", "coretracer")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain the body to keep the connection reusable
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("datadog agent responded with HTTP status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() {
+		close(e.stopped)
+		e.httpClient.CloseIdleConnections()
+	})
+
+	return nil
+}
+
+func groupByTrace(spans []sdktrace.ReadOnlySpan) [][]sdktrace.ReadOnlySpan {
+	traces := make([][]sdktrace.ReadOnlySpan, 0, 1)
+	index := make(map[oteltracer.TraceID]int)
+
+	for _, span := range spans {
+		traceID := span.SpanContext().TraceID()
+
+		i, ok := index[traceID]
+		if !ok {
+			i = len(traces)
+			index[traceID] = i
+			traces = append(traces, nil)
+		}
+
+		traces[i] = append(traces[i], span)
+	}
+
+	return traces
+}
+
+// ddSpan holds Datadog fields of a span before being encoded.
+type ddSpan struct {
+	service  string
+	name     string
+	resource string
+	spanType string
+	isError  bool
+	meta     map[string]string
+	metrics  map[string]float64
+}
+
+func (e *Exporter) convertSpan(span sdktrace.ReadOnlySpan) ddSpan {
+	s := ddSpan{
+		service:  e.serviceName,
+		name:     operationName(span),
+		resource: span.Name(),
+		spanType: "custom",
+		isError:  span.Status().Code == otelcodes.Error,
+		meta:     make(map[string]string),
+		metrics:  make(map[string]float64),
+	}
+
+	// resource attributes go first, so span attributes take precedence
+	attributes := append(span.Resource().Attributes(), span.Attributes()...)
+
+	for _, attr := range attributes {
+		switch attr.Key {
+		case "service.name":
+			s.service = attr.Value.AsString()
+		case "deployment.environment":
+			s.meta["env"] = attr.Value.AsString()
+		case "service.version":
+			s.meta["version"] = attr.Value.AsString()
+		case "resource.name":
+			s.resource = attr.Value.AsString()
+		case "operation.name":
+			s.name = attr.Value.AsString()
+		case "span.type":
+			s.spanType = attr.Value.AsString()
+		default:
+			setTag(s.meta, s.metrics, string(attr.Key), attr.Value)
+		}
+	}
+
+	if _, ok := s.meta["env"]; !ok && len(e.envName) > 0 {
+		s.meta["env"] = e.envName
+	}
+
+	if _, ok := s.meta["version"]; !ok && len(e.version) > 0 {
+		s.meta["version"] = e.version
+	}
+
+	if s.isError && len(span.Status().Description) > 0 {
+		s.meta["error.message"] = span.Status().Description
+	}
+
+	// the last recorded error wins, Datadog keeps a single error per span
+	for _, event := range span.Events() {
+		if event.Name != "exception" {
+			continue
+		}
+
+		for _, attr := range event.Attributes {
+			switch attr.Key {
+			case "exception.message":
+				s.meta["error.message"] = attr.Value.AsString()
+			case "exception.type":
+				s.meta["error.type"] = attr.Value.AsString()
+			case "exception.stacktrace":
+				s.meta["error.stack"] = attr.Value.AsString()
+			}
+		}
+	}
+
+	// a stuck function is reported by the watchdog as a span attribute
+	if errType, ok := s.meta["exception.type"]; ok {
+		s.meta["error.type"] = errType
+		delete(s.meta, "exception.type")
+	}
+
+	if kind := span.SpanKind(); kind != oteltracer.SpanKindUnspecified {
+		s.meta["span.kind"] = kind.String()
+	}
+
+	if parent := span.Parent(); !parent.IsValid() || parent.IsRemote() {
+		s.metrics["_top_level"] = 1
+		s.metrics["_sampling_priority_v1"] = 1
+	}
+
+	// Datadog IDs are 64-bit, the upper half of the trace ID goes into a propagated tag
+	traceID := span.SpanContext().TraceID()
+	s.meta["_dd.p.tid"] = hex.EncodeToString(traceID[:8])
+
+	return s
+}
+
+func operationName(span sdktrace.ReadOnlySpan) string {
+	scope := span.InstrumentationScope().Name
+	if len(scope) == 0 {
+		scope = "coretracer"
+	}
+
+	kind := span.SpanKind()
+	if kind == oteltracer.SpanKindUnspecified {
+		kind = oteltracer.SpanKindInternal
+	}
+
+	return scope + "." + kind.String()
+}
+
+func setTag(meta map[string]string, metrics map[string]float64, k string, v otelattribute.Value) {
+	switch v.Type() {
+	case otelattribute.INT64:
+		metrics[k] = float64(v.AsInt64())
+	case otelattribute.FLOAT64:
+		metrics[k] = v.AsFloat64()
+	case otelattribute.STRING:
+		meta[k] = v.AsString()
+	default:
+		meta[k] = v.Emit()
+	}
+}
+
+func (e *Exporter) appendSpan(b []byte, span sdktrace.ReadOnlySpan) []byte {
+	s := e.convertSpan(span)
+
+	var errFlag int32
+	if s.isError {
+		errFlag = 1
+	}
+
+	var parentID uint64
+	if parent := span.Parent(); parent.IsValid() {
+		parentID = spanIDToUint64(parent.SpanID())
+	}
+
+	b = msgp.AppendMapHeader(b, 12)
+	b = msgp.AppendString(b, "service")
+	b = msgp.AppendString(b, s.service)
+	b = msgp.AppendString(b, "name")
+	b = msgp.AppendString(b, s.name)
+	b = msgp.AppendString(b, "resource")
+	b = msgp.AppendString(b, s.resource)
+	b = msgp.AppendString(b, "type")
+	b = msgp.AppendString(b, s.spanType)
+	b = msgp.AppendString(b, "trace_id")
+	b = msgp.AppendUint64(b, traceIDToUint64(span.SpanContext().TraceID()))
+	b = msgp.AppendString(b, "span_id")
+	b = msgp.AppendUint64(b, spanIDToUint64(span.SpanContext().SpanID()))
+	b = msgp.AppendString(b, "parent_id")
+	b = msgp.AppendUint64(b, parentID)
+	b = msgp.AppendString(b, "start")
+	b = msgp.AppendInt64(b, span.StartTime().UnixNano())
+	b = msgp.AppendString(b, "duration")
+	b = msgp.AppendInt64(b, span.EndTime().Sub(span.StartTime()).Nanoseconds())
+	b = msgp.AppendString(b, "error")
+	b = msgp.AppendInt32(b, errFlag)
+
+	b = msgp.AppendString(b, "meta")
+	b = msgp.AppendMapHeader(b, uint32(len(s.meta)))
+	for k, v := range s.meta {
+		b = msgp.AppendString(b, k)
+		b = msgp.AppendString(b, v)
+	}
+
+	b = msgp.AppendString(b, "metrics")
+	b = msgp.AppendMapHeader(b, uint32(len(s.metrics)))
+	for k, v := range s.metrics {
+		b = msgp.AppendString(b, k)
+		b = msgp.AppendFloat64(b, v)
+	}
+
+	return b
+}
+
+// traceIDToUint64 takes the lower 64 bits of the trace ID, as Datadog does for W3C trace IDs.
+func traceIDToUint64(traceID oteltracer.TraceID) uint64 {
+	return binary.BigEndian.Uint64(traceID[8:])
+}
+
+func spanIDToUint64(spanID oteltracer.SpanID) uint64 {
+	return binary.BigEndian.Uint64(spanID[:])
+}
@@ -0,0 +1,153 @@
+package datadog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+// newAgentStub decodes v0.4 payloads into generic spans, keyed by resource name.
+func newAgentStub(t *testing.T) (*httptest.Server, func() map[string]map[string]any) {
+	var (
+		mux   sync.Mutex
+		spans = make(map[string]map[string]any)
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, tracesPath, r.URL.Path)
+		require.Equal(t, "application/msgpack", r.Header.Get("Content-Type"))
+		require.NotEmpty(t, r.Header.Get("X-Datadog-Trace-Count"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		payload, rest, err := msgp.ReadIntfBytes(body)
+		require.NoError(t, err)
+		require.Empty(t, rest)
+
+		mux.Lock()
+		for _, trace := range payload.([]any) {
+			for _, span := range trace.([]any) {
+				span := span.(map[string]any)
+				spans[span["resource"].(string)] = span
+			}
+		}
+		mux.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() map[string]map[string]any {
+		mux.Lock()
+		defer mux.Unlock()
+
+		return spans
+	}
+}
+
+func parentFunc(ctx context.Context) {
+	defer coretracer.TraceWithName(&ctx, "parentFunc", coretracer.NewTags(map[string]any{
+		"svc":          "myService",
+		"block_height": 42,
+	}))()
+
+	childFunc(ctx)
+}
+
+func childFunc(ctx context.Context) {
+	defer coretracer.TraceWithName(&ctx, "childFunc")()
+
+	coretracer.TraceError(ctx, errors.New("boom"))
+}
+
+func TestExporter_SendsTracesToAgent(t *testing.T) {
+	srv, agentSpans := newAgentStub(t)
+
+	coretracer.Enable(&coretracer.Config{
+		ServiceName:    "dd-test",
+		ServiceVersion: "1.2.3",
+		EnvName:        "staging",
+		CollectorDSN:   strings.TrimPrefix(srv.URL, "http://"),
+	}, InitExporter)
+
+	parentFunc(context.Background())
+	coretracer.Close()
+
+	spans := agentSpans()
+	require.Len(t, spans, 2)
+
+	parent, child := spans["parentFunc"], spans["childFunc"]
+	require.NotNil(t, parent)
+	require.NotNil(t, child)
+
+	require.Equal(t, "dd-test", parent["service"])
+	require.Equal(t, "coretracer.internal", parent["name"])
+	require.Equal(t, "custom", parent["type"])
+	require.Equal(t, parent["trace_id"], child["trace_id"])
+	require.Equal(t, parent["span_id"], child["parent_id"])
+	require.EqualValues(t, 0, parent["parent_id"])
+	require.EqualValues(t, 0, parent["error"])
+	require.Positive(t, parent["duration"])
+
+	parentMeta := parent["meta"].(map[string]any)
+	require.Equal(t, "staging", parentMeta["env"])
+	require.Equal(t, "1.2.3", parentMeta["version"])
+	require.Equal(t, "myService", parentMeta["svc"])
+	require.Len(t, parentMeta["_dd.p.tid"], 16)
+
+	parentMetrics := parent["metrics"].(map[string]any)
+	require.EqualValues(t, 42, parentMetrics["block_height"])
+	require.EqualValues(t, 1, parentMetrics["_top_level"])
+
+	require.EqualValues(t, 1, child["error"])
+
+	childMeta := child["meta"].(map[string]any)
+	require.Equal(t, "boom", childMeta["error.message"])
+	require.Equal(t, "*errors.errorString", childMeta["error.type"])
+	require.Contains(t, childMeta["error.stack"], "exporters/datadog.childFunc")
+	require.NotContains(t, child["metrics"], "_top_level")
+}
+
+func TestExporter_ReportsAgentErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	exporter := NewExporter(&coretracer.Config{
+		CollectorDSN: strings.TrimPrefix(srv.URL, "http://"),
+	})
+
+	rec := newRecordedSpan(t)
+	err := exporter.ExportSpans(context.Background(), rec)
+	require.ErrorContains(t, err, "503")
+
+	require.NoError(t, exporter.Shutdown(context.Background()))
+	require.ErrorIs(t, exporter.ExportSpans(context.Background(), rec), errShutdown, "Expected spans after shutdown to fail")
+}
+
+func newRecordedSpan(t *testing.T) []sdktrace.ReadOnlySpan {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+	})
+
+	_, span := tp.Tracer("test").Start(context.Background(), "recorded")
+	span.End()
+
+	return recorder.Ended()
+}
@@ -2,25 +2,34 @@ package otel
 
 import (
 	"context"
-	"log/slog"
+	"fmt"
 
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"google.golang.org/grpc/credentials"
 
-	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"github.com/InjectiveLabs/coretracer"
 )
 
+// InitExporter sends spans to the OpenTelemetry collector over OTLP/gRPC.
+// The exporter is rebuilt by coretracer.Reconfigure when the collector settings change.
 func InitExporter(cfg *coretracer.Config) coretracer.ExporterShutdownFn {
+	return coretracer.InitMultiExporter(NewSpanExporter)(cfg)
+}
+
+// NewSpanExporter creates an OTLP/gRPC span exporter, see coretracer.SpanExporterFn.
+func NewSpanExporter(cfg *coretracer.Config) (sdktrace.SpanExporter, error) {
 	var secureOption otlptracegrpc.Option
 
 	if cfg.CollectorSecureSSL {
-		secureOption = otlptracegrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
+		tlsCfg, err := cfg.ClientTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid collector TLS config: %w", err)
+		}
+
+		secureOption = otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsCfg))
 	} else {
 		secureOption = otlptracegrpc.WithInsecure()
 	}
@@ -34,46 +43,34 @@ func InitExporter(cfg *coretracer.Config) coretracer.ExporterShutdownFn {
 		clientOpts = append(clientOpts, otlptracegrpc.WithHeaders(cfg.CollectorHeaders))
 	}
 
-	exporter, err := otlptrace.New(
-		context.Background(),
-		otlptracegrpc.NewClient(clientOpts...),
-	)
-	if err != nil {
-		slog.Warn("coretracer: otel exporter: failed to create exporter", "error", err)
-		return emptyShutdownFn()
-	}
-
-	resources, err := resource.New(
-		context.Background(),
-		resource.WithAttributes(
-			attribute.String("service.name", cfg.ServiceName),
-			attribute.String("service.version", cfg.ServiceVersion),
-			attribute.String("deployment.environment", cfg.EnvName),
-			attribute.String("deployment.cluster_id", cfg.ClusterID),
-		),
-	)
-	if err != nil {
-		slog.Warn("coretracer: otel exporter: could not set resources", "error", err)
-		return emptyShutdownFn()
-	}
+	return newSpanExporter(cfg, otlptracegrpc.NewClient(clientOpts...))
+}
 
-	traceProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-		sdktrace.WithBatcher(exporter),
-		sdktrace.WithResource(resources),
-	)
+var _ coretracer.ExporterPinger = (*spanExporter)(nil)
 
-	otel.SetTracerProvider(traceProvider)
+// spanExporter adds the collector reachability check to the OTLP exporter.
+type spanExporter struct {
+	*otlptrace.Exporter
 
-	return func(ctx context.Context) error {
-		if err := traceProvider.ForceFlush(ctx); err != nil {
-			slog.Warn("coretracer: otel exporter: failed to force flush traces", "error", err)
-		}
+	client otlptrace.Client
+}
 
-		return exporter.Shutdown(ctx)
+// newSpanExporter starts the OTLP exporter on top of the client, shared between
+// gRPC and HTTP flavours.
+func newSpanExporter(cfg *coretracer.Config, client otlptrace.Client) (sdktrace.SpanExporter, error) {
+	exporter, err := otlptrace.New(context.Background(), withSpool(cfg, client))
+	if err != nil {
+		return nil, err
 	}
+
+	return &spanExporter{
+		Exporter: exporter,
+		client:   client,
+	}, nil
 }
 
-func emptyShutdownFn() func(ctx context.Context) error {
-	return func(ctx context.Context) error { return nil }
+// Ping implements coretracer.ExporterPinger, by uploading an empty batch.
+// The spool is bypassed, so a failed ping is never stored.
+func (e *spanExporter) Ping(ctx context.Context) error {
+	return e.client.UploadTraces(ctx, nil)
 }
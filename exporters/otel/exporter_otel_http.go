@@ -0,0 +1,209 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+const (
+	defaultHTTPURLPath = "/v1/traces"
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+// InitHTTPExporter is the OTLP/HTTP counterpart of InitExporter, for environments
+// where gRPC is blocked by the ingress. Payloads are encoded as protobuf, unless
+// cfg.CollectorProtocol is set to coretracer.CollectorProtocolHTTPJSON.
+func InitHTTPExporter(cfg *coretracer.Config) coretracer.ExporterShutdownFn {
+	return coretracer.InitMultiExporter(NewHTTPSpanExporter)(cfg)
+}
+
+// NewHTTPSpanExporter creates an OTLP/HTTP span exporter, see coretracer.SpanExporterFn.
+func NewHTTPSpanExporter(cfg *coretracer.Config) (sdktrace.SpanExporter, error) {
+	urlPath := cfg.CollectorURLPath
+	if len(urlPath) == 0 {
+		urlPath = defaultHTTPURLPath
+	}
+
+	var tlsCfg *tls.Config
+
+	if cfg.CollectorSecureSSL {
+		var err error
+		if tlsCfg, err = cfg.ClientTLSConfig(); err != nil {
+			return nil, fmt.Errorf("invalid collector TLS config: %w", err)
+		}
+	}
+
+	var client otlptrace.Client
+
+	if cfg.CollectorProtocol == coretracer.CollectorProtocolHTTPJSON {
+		client = newJSONClient(cfg, urlPath, tlsCfg)
+	} else {
+		clientOpts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(cfg.CollectorDSN),
+			otlptracehttp.WithURLPath(urlPath),
+		}
+
+		if tlsCfg != nil {
+			clientOpts = append(clientOpts, otlptracehttp.WithTLSClientConfig(tlsCfg))
+		} else {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+
+		if len(cfg.CollectorHeaders) > 0 {
+			clientOpts = append(clientOpts, otlptracehttp.WithHeaders(cfg.CollectorHeaders))
+		}
+
+		client = otlptracehttp.NewClient(clientOpts...)
+	}
+
+	return newSpanExporter(cfg, client)
+}
+
+var _ otlptrace.Client = (*jsonClient)(nil)
+
+// jsonClient uploads OTLP/JSON payloads. The upstream otlptracehttp client
+// only speaks protobuf, so JSON encoding is done here.
+type jsonClient struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+func newJSONClient(cfg *coretracer.Config, urlPath string, tlsCfg *tls.Config) *jsonClient {
+	scheme := "http"
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if tlsCfg != nil {
+		scheme = "https"
+		transport.TLSClientConfig = tlsCfg
+	}
+
+	return &jsonClient{
+		url:     scheme + "://" + cfg.CollectorDSN + urlPath,
+		headers: cfg.CollectorHeaders,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   defaultHTTPTimeout,
+		},
+	}
+}
+
+// Start implements otlptrace.Client.
+func (c *jsonClient) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop implements otlptrace.Client.
+func (c *jsonClient) Stop(ctx context.Context) error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// UploadTraces implements otlptrace.Client.
+func (c *jsonClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	body, err := marshalTracesJSON(protoSpans)
+	if err != nil {
+		return fmt.Errorf("failed to encode OTLP/JSON payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain the body to keep the connection reusable
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector responded with HTTP status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// marshalTracesJSON encodes spans per OTLP/JSON rules, which differ from the canonical
+// protobuf JSON mapping: enums are numbers and trace/span IDs are hex strings, not base64.
+func marshalTracesJSON(protoSpans []*tracepb.ResourceSpans) ([]byte, error) {
+	payload, err := protojson.MarshalOptions{
+		UseEnumNumbers: true,
+	}.Marshal(&coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: protoSpans,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, err
+	}
+
+	if err := hexEncodeIDs(doc); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+var otlpIDFields = map[string]bool{
+	"traceId":      true,
+	"spanId":       true,
+	"parentSpanId": true,
+}
+
+func hexEncodeIDs(node any) error {
+	switch node := node.(type) {
+	case map[string]any:
+		for k, v := range node {
+			if s, ok := v.(string); ok && otlpIDFields[k] {
+				id, err := base64.StdEncoding.DecodeString(s)
+				if err != nil {
+					return fmt.Errorf("malformed %s: %w", k, err)
+				}
+
+				node[k] = hex.EncodeToString(id)
+				continue
+			}
+
+			if err := hexEncodeIDs(v); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, v := range node {
+			if err := hexEncodeIDs(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package otel
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+type capturedRequest struct {
+	path        string
+	contentType string
+	header      http.Header
+	body        []byte
+}
+
+func newCollectorStub(t *testing.T) (*httptest.Server, func() []capturedRequest) {
+	return startCollectorStub(t, false)
+}
+
+func startCollectorStub(t *testing.T, withTLS bool) (*httptest.Server, func() []capturedRequest) {
+	var (
+		mux      sync.Mutex
+		requests []capturedRequest
+	)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mux.Lock()
+		requests = append(requests, capturedRequest{
+			path:        r.URL.Path,
+			contentType: r.Header.Get("Content-Type"),
+			header:      r.Header.Clone(),
+			body:        body,
+		})
+		mux.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	if withTLS {
+		srv.StartTLS()
+	} else {
+		srv.Start()
+	}
+	t.Cleanup(srv.Close)
+
+	return srv, func() []capturedRequest {
+		mux.Lock()
+		defer mux.Unlock()
+
+		return append([]capturedRequest(nil), requests...)
+	}
+}
+
+func traceSomething() {
+	ctx := context.Background()
+	defer coretracer.TraceWithName(&ctx, "http-exporter-test", coretracer.NewTag("test.key", "test.value"))()
+
+	func(ctx context.Context) {
+		defer coretracer.TraceWithName(&ctx, "http-exporter-test-child")()
+	}(ctx)
+}
+
+func TestInitHTTPExporter_Protobuf(t *testing.T) {
+	srv, requests := newCollectorStub(t)
+
+	coretracer.Enable(&coretracer.Config{
+		ServiceName:      "http-test",
+		CollectorDSN:     strings.TrimPrefix(srv.URL, "http://"),
+		CollectorHeaders: map[string]string{"X-Api-Key": "secret"},
+	}, InitHTTPExporter)
+
+	traceSomething()
+	coretracer.Close()
+
+	reqs := requests()
+	require.NotEmpty(t, reqs, "Expected collector to receive an export request")
+	require.Equal(t, defaultHTTPURLPath, reqs[0].path)
+	require.Equal(t, "application/x-protobuf", reqs[0].contentType)
+	require.Equal(t, "secret", reqs[0].header.Get("X-Api-Key"))
+
+	var spanNames []string
+	for _, req := range reqs {
+		var payload coltracepb.ExportTraceServiceRequest
+		require.NoError(t, proto.Unmarshal(req.body, &payload))
+
+		for _, rs := range payload.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					spanNames = append(spanNames, span.Name)
+				}
+			}
+		}
+	}
+
+	require.ElementsMatch(t, []string{"http-exporter-test", "http-exporter-test-child"}, spanNames)
+}
+
+func TestInitHTTPExporter_JSON(t *testing.T) {
+	srv, requests := newCollectorStub(t)
+
+	coretracer.Enable(&coretracer.Config{
+		ServiceName:       "http-test",
+		CollectorDSN:      strings.TrimPrefix(srv.URL, "http://"),
+		CollectorProtocol: coretracer.CollectorProtocolHTTPJSON,
+		CollectorURLPath:  "/custom/traces",
+		CollectorHeaders:  map[string]string{"X-Api-Key": "secret"},
+	}, InitHTTPExporter)
+
+	traceSomething()
+	coretracer.Close()
+
+	reqs := requests()
+	require.NotEmpty(t, reqs, "Expected collector to receive an export request")
+	require.Equal(t, "/custom/traces", reqs[0].path)
+	require.Equal(t, "application/json", reqs[0].contentType)
+	require.Equal(t, "secret", reqs[0].header.Get("X-Api-Key"))
+
+	type jsonSpan struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		Kind         int    `json:"kind"`
+	}
+
+	var payload struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []jsonSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+
+	spans := make(map[string]jsonSpan)
+	for _, req := range reqs {
+		require.NoError(t, json.Unmarshal(req.body, &payload))
+
+		for _, rs := range payload.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					spans[span.Name] = span
+				}
+			}
+		}
+	}
+
+	require.Len(t, spans, 2)
+
+	parent, child := spans["http-exporter-test"], spans["http-exporter-test-child"]
+	require.Len(t, parent.TraceID, 32, "Expected trace ID to be hex encoded")
+	require.Len(t, parent.SpanID, 16, "Expected span ID to be hex encoded")
+	require.Equal(t, parent.TraceID, child.TraceID)
+	require.Equal(t, parent.SpanID, child.ParentSpanID)
+	require.Equal(t, 1, parent.Kind, "Expected span kind to be encoded as a number")
+}
+
+func TestInitHTTPExporter_CustomCA(t *testing.T) {
+	srv, requests := startCollectorStub(t, true)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	coretracer.Enable(&coretracer.Config{
+		ServiceName:        "http-test",
+		CollectorDSN:       strings.TrimPrefix(srv.URL, "https://"),
+		CollectorSecureSSL: true,
+		CollectorTLS: coretracer.TLSConfig{
+			CAPEM: caPEM,
+			// the test certificate is issued for example.com
+			ServerName: "example.com",
+		},
+	}, InitHTTPExporter)
+
+	traceSomething()
+	coretracer.Close()
+
+	require.NotEmpty(t, requests(), "Expected collector to receive an export request over TLS")
+}
+
+func TestNewHTTPSpanExporter_InvalidTLS(t *testing.T) {
+	_, err := NewHTTPSpanExporter(&coretracer.Config{
+		CollectorSecureSSL: true,
+		CollectorTLS:       coretracer.TLSConfig{CAPEM: []byte("garbage")},
+	})
+	require.ErrorContains(t, err, "invalid collector TLS config")
+}
+
+func TestEnableE_PingsCollector(t *testing.T) {
+	srv, requests := newCollectorStub(t)
+
+	cfg := &coretracer.Config{
+		ServiceName:  "http-test",
+		CollectorDSN: strings.TrimPrefix(srv.URL, "http://"),
+	}
+
+	require.NoError(t, coretracer.EnableE(cfg, coretracer.InitExporters(NewHTTPSpanExporter)))
+	require.Len(t, requests(), 1, "Expected an empty export to check the collector")
+
+	traceSomething()
+	coretracer.Close()
+
+	srv.Close()
+
+	err := coretracer.EnableE(cfg, coretracer.InitExporters(NewHTTPSpanExporter))
+	require.ErrorContains(t, err, "can't reach its destination")
+	require.Nil(t, coretracer.DefaultTracer())
+}
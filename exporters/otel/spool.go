@@ -0,0 +1,530 @@
+package otel
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+const (
+	defaultSpoolMaxSize        = 64 << 20
+	defaultSpoolMaxAge         = 24 * time.Hour
+	defaultSpoolReplayInterval = 5 * time.Second
+	defaultSpoolReplayTimeout  = 10 * time.Second
+
+	spoolFileExt = ".spool"
+	spoolTempExt = ".tmp"
+)
+
+// spoolMagic starts every spool file, followed by the header and the payload:
+// magic | created at (unix nano, int64) | payload length (uint32) | payload CRC32 (uint32) | payload
+var spoolMagic = []byte("CTSPOOL1")
+
+const spoolHeaderSize = 8 + 8 + 4 + 4
+
+var errSpoolFull = errors.New("batch exceeds the spool size limit")
+
+// SpoolCounters are the totals of all spools in the process.
+type SpoolCounters struct {
+	// Spooled is the number of batches written to disk after a failed export.
+	Spooled uint64
+	// Replayed is the number of spooled batches delivered to the collector.
+	Replayed uint64
+	// Discarded is the number of batches lost due to size or age limits, or corruption.
+	Discarded uint64
+}
+
+var spoolCounters struct {
+	spooled   atomic.Uint64
+	replayed  atomic.Uint64
+	discarded atomic.Uint64
+}
+
+// SpoolStats returns the spool counters, see coretracer.SpoolConfig.
+func SpoolStats() SpoolCounters {
+	return SpoolCounters{
+		Spooled:   spoolCounters.spooled.Load(),
+		Replayed:  spoolCounters.replayed.Load(),
+		Discarded: spoolCounters.discarded.Load(),
+	}
+}
+
+var _ otlptrace.Client = (*spoolClient)(nil)
+
+// spoolClient wraps an OTLP client, batches that fail to upload are written to disk
+// and replayed in order by a background loop. While there are spooled batches,
+// new ones are queued behind them, so the collector receives spans in order.
+type spoolClient struct {
+	client otlptrace.Client
+	cfg    coretracer.SpoolConfig
+	queue  *spoolQueue
+
+	wakeC    chan struct{}
+	stopC    chan struct{}
+	stopOnce sync.Once
+	doneC    chan struct{}
+}
+
+// spoolQueue is the on-disk queue of a spool directory. Clients spooling into the same directory,
+// e.g. the exporter rebuilt by coretracer.Reconfigure and the one it replaces, share it:
+// the batches are recovered from disk by the first client only, and replayed by one client at a time.
+type spoolQueue struct {
+	dir  string
+	refs int // guarded by spoolQueues.mux
+
+	// replayMux is held by the client replaying the queue
+	replayMux sync.Mutex
+
+	mux     sync.Mutex
+	files   []spoolFile
+	size    int64
+	nextSeq uint64
+}
+
+// spoolQueues holds the queues of the directories in use, by the cleaned path.
+var spoolQueues = struct {
+	mux   sync.Mutex
+	byDir map[string]*spoolQueue
+}{
+	byDir: make(map[string]*spoolQueue),
+}
+
+// acquireSpoolQueue returns the queue of the directory, recovered from disk unless it's already in use.
+func acquireSpoolQueue(cfg coretracer.SpoolConfig) (*spoolQueue, error) {
+	spoolQueues.mux.Lock()
+	defer spoolQueues.mux.Unlock()
+
+	dir := filepath.Clean(cfg.Dir)
+	if q, ok := spoolQueues.byDir[dir]; ok {
+		q.refs++
+		return q, nil
+	}
+
+	q := &spoolQueue{dir: dir, refs: 1}
+	if err := q.recover(cfg.MaxSize); err != nil {
+		return nil, err
+	}
+
+	spoolQueues.byDir[dir] = q
+
+	return q, nil
+}
+
+// release drops the queue once its last client stops, so the next one recovers it from disk.
+func (q *spoolQueue) release() {
+	spoolQueues.mux.Lock()
+	defer spoolQueues.mux.Unlock()
+
+	if q.refs--; q.refs == 0 {
+		delete(spoolQueues.byDir, q.dir)
+	}
+}
+
+type spoolFile struct {
+	seq  uint64
+	size int64
+}
+
+// withSpool wraps the client into a spool, if enabled by the config.
+func withSpool(cfg *coretracer.Config, client otlptrace.Client) otlptrace.Client {
+	if len(cfg.CollectorSpool.Dir) == 0 {
+		return client
+	}
+
+	return newSpoolClient(client, cfg.CollectorSpool)
+}
+
+func newSpoolClient(client otlptrace.Client, cfg coretracer.SpoolConfig) *spoolClient {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultSpoolMaxSize
+	}
+
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = defaultSpoolMaxAge
+	}
+
+	if cfg.ReplayInterval <= 0 {
+		cfg.ReplayInterval = defaultSpoolReplayInterval
+	}
+
+	return &spoolClient{
+		client: client,
+		cfg:    cfg,
+		wakeC:  make(chan struct{}, 1),
+		stopC:  make(chan struct{}),
+		doneC:  make(chan struct{}),
+	}
+}
+
+// Start implements otlptrace.Client. Picks up batches spooled by the previous process,
+// or by the client still running on the same directory.
+func (c *spoolClient) Start(ctx context.Context) error {
+	queue, err := acquireSpoolQueue(c.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to recover spool: %w", err)
+	}
+
+	if err := c.client.Start(ctx); err != nil {
+		queue.release()
+		return err
+	}
+
+	c.queue = queue
+
+	go c.replayLoop()
+
+	return nil
+}
+
+// Stop implements otlptrace.Client. Spooled batches stay on disk for the next process.
+func (c *spoolClient) Stop(ctx context.Context) error {
+	c.stopOnce.Do(func() {
+		close(c.stopC)
+	})
+
+	select {
+	case <-c.doneC:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return c.client.Stop(ctx)
+}
+
+// UploadTraces implements otlptrace.Client.
+func (c *spoolClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	c.queue.mux.Lock()
+	pending := len(c.queue.files) > 0
+	c.queue.mux.Unlock()
+
+	if !pending {
+		err := c.client.UploadTraces(ctx, protoSpans)
+		if err == nil {
+			return nil
+		}
+
+		if spoolErr := c.spool(protoSpans); spoolErr != nil {
+			return errors.Join(err, spoolErr)
+		}
+
+		// not delivered yet, so not counted as exported
+		return fmt.Errorf("%w: %w", coretracer.ErrSpooled, err)
+	}
+
+	// keep the order, the batch is sent after the ones already spooled
+	if err := c.spool(protoSpans); err != nil {
+		return err
+	}
+
+	c.wake()
+
+	return coretracer.ErrSpooled
+}
+
+func (c *spoolClient) spool(protoSpans []*tracepb.ResourceSpans) error {
+	payload, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: protoSpans,
+	})
+	if err != nil {
+		spoolCounters.discarded.Add(1)
+		return fmt.Errorf("failed to encode spool batch: %w", err)
+	}
+
+	size := int64(spoolHeaderSize + len(payload))
+	if size > c.cfg.MaxSize {
+		spoolCounters.discarded.Add(1)
+		return errSpoolFull
+	}
+
+	q := c.queue
+
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	// make room by discarding the oldest batches
+	for q.size+size > c.cfg.MaxSize && len(q.files) > 0 {
+		q.removeLocked(q.files[0])
+		spoolCounters.discarded.Add(1)
+	}
+
+	file := spoolFile{seq: q.nextSeq, size: size}
+	if err := writeSpoolFile(q.path(file.seq), time.Now(), payload); err != nil {
+		spoolCounters.discarded.Add(1)
+		return fmt.Errorf("failed to write spool batch: %w", err)
+	}
+
+	q.nextSeq++
+	q.files = append(q.files, file)
+	q.size += size
+
+	spoolCounters.spooled.Add(1)
+
+	return nil
+}
+
+func (c *spoolClient) wake() {
+	select {
+	case c.wakeC <- struct{}{}:
+	default:
+	}
+}
+
+func (c *spoolClient) replayLoop() {
+	defer close(c.doneC)
+	defer c.queue.release()
+
+	ticker := time.NewTicker(c.cfg.ReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopC:
+			return
+		case <-ticker.C:
+		case <-c.wakeC:
+		}
+
+		c.replay()
+	}
+}
+
+// replay sends spooled batches in order, until the spool is drained or an upload fails.
+// Another client replaying the same queue is left to finish, e.g. the one being replaced.
+func (c *spoolClient) replay() {
+	q := c.queue
+
+	if !q.replayMux.TryLock() {
+		return
+	}
+	defer q.replayMux.Unlock()
+
+	for {
+		select {
+		case <-c.stopC:
+			return
+		default:
+		}
+
+		q.mux.Lock()
+		if len(q.files) == 0 {
+			q.mux.Unlock()
+			return
+		}
+
+		file := q.files[0]
+		q.mux.Unlock()
+
+		createdAt, payload, err := readSpoolFile(q.path(file.seq))
+		if err != nil {
+			slog.Warn("coretracer: otel exporter: discarding corrupted spool batch", "seq", file.seq, "error", err)
+			q.discard(file)
+			continue
+		}
+
+		if time.Since(createdAt) > c.cfg.MaxAge {
+			q.discard(file)
+			continue
+		}
+
+		req := new(coltracepb.ExportTraceServiceRequest)
+		if err := proto.Unmarshal(payload, req); err != nil {
+			slog.Warn("coretracer: otel exporter: discarding corrupted spool batch", "seq", file.seq, "error", err)
+			q.discard(file)
+			continue
+		}
+
+		ctx, cancelFn := context.WithTimeout(context.Background(), defaultSpoolReplayTimeout)
+		err = c.client.UploadTraces(ctx, req.ResourceSpans)
+		cancelFn()
+
+		if err != nil {
+			// collector is still unreachable, retry on the next tick
+			return
+		}
+
+		q.mux.Lock()
+		if q.removeLocked(file) {
+			spoolCounters.replayed.Add(1)
+		}
+		q.mux.Unlock()
+	}
+}
+
+func (q *spoolQueue) discard(file spoolFile) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if q.removeLocked(file) {
+		spoolCounters.discarded.Add(1)
+	}
+}
+
+// removeLocked deletes the file from the head of the queue, if it's still there:
+// it might have been discarded by spool in the meantime.
+func (q *spoolQueue) removeLocked(file spoolFile) bool {
+	if len(q.files) == 0 || q.files[0].seq != file.seq {
+		return false
+	}
+
+	q.files = q.files[1:]
+	q.size -= file.size
+
+	if err := os.Remove(q.path(file.seq)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("coretracer: otel exporter: failed to remove spool batch", "seq", file.seq, "error", err)
+	}
+
+	return true
+}
+
+// recover loads the batches left on disk. Partially written files are removed,
+// corrupted ones are detected and discarded when replayed.
+func (q *spoolQueue) recover(maxSize int64) error {
+	if err := os.MkdirAll(q.dir, 0o755); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if strings.HasSuffix(name, spoolTempExt) {
+			_ = os.Remove(filepath.Join(q.dir, name))
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolFileExt), 10, 64)
+		if err != nil || !strings.HasSuffix(name, spoolFileExt) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		q.files = append(q.files, spoolFile{seq: seq, size: info.Size()})
+		q.size += info.Size()
+	}
+
+	sort.Slice(q.files, func(i, j int) bool {
+		return q.files[i].seq < q.files[j].seq
+	})
+
+	if len(q.files) > 0 {
+		q.nextSeq = q.files[len(q.files)-1].seq + 1
+	}
+
+	// the limit might have been lowered since the previous run
+	for q.size > maxSize && len(q.files) > 0 {
+		q.removeLocked(q.files[0])
+		spoolCounters.discarded.Add(1)
+	}
+
+	return nil
+}
+
+func (q *spoolQueue) path(seq uint64) string {
+	// zero padded, so files are listed in order
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, spoolFileExt))
+}
+
+// writeSpoolFile writes into a temporary file first, so a crash never leaves
+// a truncated batch under the final name.
+func writeSpoolFile(path string, createdAt time.Time, payload []byte) error {
+	buf := make([]byte, spoolHeaderSize, spoolHeaderSize+len(payload))
+	copy(buf, spoolMagic)
+	binary.BigEndian.PutUint64(buf[8:], uint64(createdAt.UnixNano()))
+	binary.BigEndian.PutUint32(buf[16:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[20:], crc32.ChecksumIEEE(payload))
+	buf = append(buf, payload...)
+
+	// synced before the batch is reported as spooled, so a crash doesn't lose it
+	tmpPath := path + spoolTempExt
+	if err := writeFileSync(tmpPath, buf); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// the rename is durable once the directory is synced, not supported everywhere, e.g. on Windows
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+
+	return nil
+}
+
+// writeFileSync is os.WriteFile flushing the file to disk before closing it.
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func readSpoolFile(path string) (time.Time, []byte, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	if len(buf) < spoolHeaderSize || string(buf[:8]) != string(spoolMagic) {
+		return time.Time{}, nil, errors.New("malformed header")
+	}
+
+	createdAt := time.Unix(0, int64(binary.BigEndian.Uint64(buf[8:])))
+	length := binary.BigEndian.Uint32(buf[16:])
+	checksum := binary.BigEndian.Uint32(buf[20:])
+
+	payload := buf[spoolHeaderSize:]
+	if uint32(len(payload)) != length {
+		return time.Time{}, nil, fmt.Errorf("payload length mismatch: expected %d, got %d", length, len(payload))
+	}
+
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return time.Time{}, nil, errors.New("payload checksum mismatch")
+	}
+
+	return createdAt, payload, nil
+}
@@ -0,0 +1,247 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+// flakyClient is an OTLP client stub, that fails uploads while the collector is down.
+type flakyClient struct {
+	mux      sync.Mutex
+	down     bool
+	received []string
+}
+
+func (c *flakyClient) Start(ctx context.Context) error { return nil }
+func (c *flakyClient) Stop(ctx context.Context) error  { return nil }
+
+func (c *flakyClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.down {
+		return errors.New("collector unreachable")
+	}
+
+	c.received = append(c.received, batchName(protoSpans))
+
+	return nil
+}
+
+func (c *flakyClient) setDown(down bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.down = down
+}
+
+func (c *flakyClient) receivedBatches() []string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return append([]string(nil), c.received...)
+}
+
+func testBatch(name string) []*tracepb.ResourceSpans {
+	return []*tracepb.ResourceSpans{{
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Spans: []*tracepb.Span{{Name: name}},
+		}},
+	}}
+}
+
+func batchName(protoSpans []*tracepb.ResourceSpans) string {
+	return protoSpans[0].ScopeSpans[0].Spans[0].Name
+}
+
+func startSpool(t *testing.T, client *flakyClient, cfg coretracer.SpoolConfig) *spoolClient {
+	spool := newSpoolClient(client, cfg)
+	require.NoError(t, spool.Start(context.Background()))
+	t.Cleanup(func() {
+		_ = spool.Stop(context.Background())
+	})
+
+	return spool
+}
+
+func spoolFiles(t *testing.T, dir string) []string {
+	names, err := filepath.Glob(filepath.Join(dir, "*"+spoolFileExt))
+	require.NoError(t, err)
+
+	return names
+}
+
+func TestSpool_ReplaysInOrder(t *testing.T) {
+	client := &flakyClient{down: true}
+	spool := startSpool(t, client, coretracer.SpoolConfig{
+		Dir:            t.TempDir(),
+		ReplayInterval: time.Hour,
+	})
+
+	before := SpoolStats()
+
+	ctx := context.Background()
+	require.ErrorIs(t, spool.UploadTraces(ctx, testBatch("first")), coretracer.ErrSpooled)
+	require.ErrorIs(t, spool.UploadTraces(ctx, testBatch("second")), coretracer.ErrSpooled)
+	require.Empty(t, client.receivedBatches())
+
+	client.setDown(false)
+
+	// queued behind the spooled batches, wakes up the replay
+	require.ErrorIs(t, spool.UploadTraces(ctx, testBatch("third")), coretracer.ErrSpooled)
+
+	require.Eventually(t, func() bool {
+		return len(client.receivedBatches()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, []string{"first", "second", "third"}, client.receivedBatches())
+	require.Empty(t, spoolFiles(t, spool.cfg.Dir))
+
+	after := SpoolStats()
+	require.EqualValues(t, 3, after.Spooled-before.Spooled)
+	require.EqualValues(t, 3, after.Replayed-before.Replayed)
+	require.EqualValues(t, 0, after.Discarded-before.Discarded)
+}
+
+func TestSpool_RecoversAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	client := &flakyClient{down: true}
+	spool := newSpoolClient(client, coretracer.SpoolConfig{Dir: dir, ReplayInterval: time.Hour})
+	require.NoError(t, spool.Start(context.Background()))
+
+	ctx := context.Background()
+	require.ErrorIs(t, spool.UploadTraces(ctx, testBatch("first")), coretracer.ErrSpooled)
+	require.ErrorIs(t, spool.UploadTraces(ctx, testBatch("corrupted")), coretracer.ErrSpooled)
+	require.ErrorIs(t, spool.UploadTraces(ctx, testBatch("last")), coretracer.ErrSpooled)
+	require.NoError(t, spool.Stop(ctx))
+
+	files := spoolFiles(t, dir)
+	require.Len(t, files, 3)
+
+	// flip a payload byte, a leftover from an interrupted write is there too
+	corrupted, err := os.ReadFile(files[1])
+	require.NoError(t, err)
+	corrupted[len(corrupted)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(files[1], corrupted, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000099.spool.tmp"), []byte("CTSP"), 0o644))
+
+	before := SpoolStats()
+
+	client.setDown(false)
+	spool = startSpool(t, client, coretracer.SpoolConfig{Dir: dir, ReplayInterval: 10 * time.Millisecond})
+
+	require.Eventually(t, func() bool {
+		return len(client.receivedBatches()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, []string{"first", "last"}, client.receivedBatches())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	after := SpoolStats()
+	require.EqualValues(t, 2, after.Replayed-before.Replayed)
+	require.EqualValues(t, 1, after.Discarded-before.Discarded)
+
+	// new batches continue the sequence of the recovered ones
+	require.EqualValues(t, 3, spool.queue.nextSeq)
+}
+
+func TestSpool_SharesDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	prevClient := &flakyClient{down: true}
+	prev := newSpoolClient(prevClient, coretracer.SpoolConfig{Dir: dir, ReplayInterval: time.Hour})
+	require.NoError(t, prev.Start(context.Background()))
+
+	ctx := context.Background()
+	require.ErrorIs(t, prev.UploadTraces(ctx, testBatch("first")), coretracer.ErrSpooled)
+	require.ErrorIs(t, prev.UploadTraces(ctx, testBatch("second")), coretracer.ErrSpooled)
+
+	// the exporter rebuilt on the same directory takes the queue over
+	nextClient := &flakyClient{}
+	next := startSpool(t, nextClient, coretracer.SpoolConfig{Dir: dir, ReplayInterval: 10 * time.Millisecond})
+	require.Same(t, prev.queue, next.queue)
+
+	require.ErrorIs(t, next.UploadTraces(ctx, testBatch("third")), coretracer.ErrSpooled)
+	require.NoError(t, prev.Stop(ctx))
+
+	require.Eventually(t, func() bool {
+		return len(nextClient.receivedBatches()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, []string{"first", "second", "third"}, nextClient.receivedBatches())
+	require.Empty(t, prevClient.receivedBatches())
+	require.Empty(t, spoolFiles(t, dir))
+}
+
+func TestSpool_Limits(t *testing.T) {
+	t.Run("size", func(t *testing.T) {
+		client := &flakyClient{down: true}
+		spool := startSpool(t, client, coretracer.SpoolConfig{
+			Dir:            t.TempDir(),
+			MaxSize:        100,
+			ReplayInterval: time.Hour,
+		})
+
+		before := SpoolStats()
+
+		ctx := context.Background()
+		for _, name := range []string{"first", "second", "third", "fourth"} {
+			require.ErrorIs(t, spool.UploadTraces(ctx, testBatch(name)), coretracer.ErrSpooled)
+		}
+
+		require.Len(t, spoolFiles(t, spool.cfg.Dir), 2, "Expected the oldest batches to be discarded")
+
+		err := spool.UploadTraces(ctx, testBatch(string(make([]byte, 100))))
+		require.ErrorIs(t, err, errSpoolFull)
+
+		after := SpoolStats()
+		require.EqualValues(t, 3, after.Discarded-before.Discarded)
+
+		client.setDown(false)
+		spool.wake()
+
+		require.Eventually(t, func() bool {
+			return len(client.receivedBatches()) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, []string{"third", "fourth"}, client.receivedBatches())
+	})
+
+	t.Run("age", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, writeSpoolFile(
+			filepath.Join(dir, "00000000000000000000.spool"),
+			time.Now().Add(-2*time.Hour),
+			[]byte{},
+		))
+
+		before := SpoolStats()
+
+		client := &flakyClient{}
+		spool := startSpool(t, client, coretracer.SpoolConfig{
+			Dir:            dir,
+			MaxAge:         time.Hour,
+			ReplayInterval: 10 * time.Millisecond,
+		})
+
+		require.Eventually(t, func() bool {
+			return len(spoolFiles(t, spool.cfg.Dir)) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		require.Empty(t, client.receivedBatches())
+		require.EqualValues(t, 1, SpoolStats().Discarded-before.Discarded)
+	})
+}
@@ -0,0 +1,302 @@
+package zipkin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltracer "go.opentelemetry.io/otel/trace"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+const (
+	defaultCollectorDSN = "localhost:9411"
+	defaultURLPath      = "/api/v2/spans"
+	defaultTimeout      = 10 * time.Second
+)
+
+// InitExporter sends spans to a Zipkin-compatible backend, using the v2 JSON API.
+// The backend address is taken from cfg.CollectorDSN, defaults to localhost:9411,
+// the URL path can be changed with cfg.CollectorURLPath.
+func InitExporter(cfg *coretracer.Config) coretracer.ExporterShutdownFn {
+	return coretracer.InitMultiExporter(NewSpanExporter)(cfg)
+}
+
+// NewSpanExporter creates a Zipkin span exporter, see coretracer.SpanExporterFn.
+func NewSpanExporter(cfg *coretracer.Config) (sdktrace.SpanExporter, error) {
+	return NewExporter(cfg), nil
+}
+
+var _ sdktrace.SpanExporter = (*Exporter)(nil)
+
+// errShutdown is returned for spans exported after Shutdown, so they are counted as failed.
+var errShutdown = errors.New("zipkin exporter is shut down")
+
+// Exporter converts finished spans into Zipkin v2 spans.
+type Exporter struct {
+	url           string
+	headers       map[string]string
+	localEndpoint *Endpoint
+	httpClient    *http.Client
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// NewExporter creates a Zipkin exporter for the backend configured in cfg.
+func NewExporter(cfg *coretracer.Config) *Exporter {
+	dsn := cfg.CollectorDSN
+	if len(dsn) == 0 {
+		dsn = defaultCollectorDSN
+	}
+
+	urlPath := cfg.CollectorURLPath
+	if len(urlPath) == 0 {
+		urlPath = defaultURLPath
+	}
+
+	scheme := "http"
+	if cfg.CollectorSecureSSL {
+		scheme = "https"
+	}
+
+	return &Exporter{
+		url:     scheme + "://" + dsn + urlPath,
+		headers: cfg.CollectorHeaders,
+		localEndpoint: &Endpoint{
+			ServiceName: cfg.ServiceName,
+		},
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		stopped:    make(chan struct{}),
+	}
+}
+
+// Span is a Zipkin v2 span.
+type Span struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Kind           string            `json:"kind,omitempty"`
+	Timestamp      int64             `json:"timestamp,omitempty"`
+	Duration       int64             `json:"duration,omitempty"`
+	LocalEndpoint  *Endpoint         `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *Endpoint         `json:"remoteEndpoint,omitempty"`
+	Annotations    []Annotation      `json:"annotations,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+// Endpoint is a Zipkin v2 network endpoint.
+type Endpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        int    `json:"port,omitempty"`
+}
+
+// Annotation is a Zipkin v2 timestamped event.
+type Annotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e *Exporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	select {
+	case <-e.stopped:
+		return errShutdown
+	default:
+	}
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	zipkinSpans := make([]Span, 0, len(spans))
+	for _, span := range spans {
+		zipkinSpans = append(zipkinSpans, e.convertSpan(span))
+	}
+
+	payload, err := json.Marshal(zipkinSpans)
+	if err != nil {
+		return fmt.Errorf("failed to encode zipkin spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain the body to keep the connection reusable
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("zipkin collector responded with HTTP status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() {
+		close(e.stopped)
+		e.httpClient.CloseIdleConnections()
+	})
+
+	return nil
+}
+
+var spanKinds = map[oteltracer.SpanKind]string{
+	oteltracer.SpanKindServer:   "SERVER",
+	oteltracer.SpanKindClient:   "CLIENT",
+	oteltracer.SpanKindProducer: "PRODUCER",
+	oteltracer.SpanKindConsumer: "CONSUMER",
+}
+
+func (e *Exporter) convertSpan(span sdktrace.ReadOnlySpan) Span {
+	spanCtx := span.SpanContext()
+
+	s := Span{
+		TraceID:       spanCtx.TraceID().String(),
+		ID:            spanCtx.SpanID().String(),
+		Name:          span.Name(),
+		Kind:          spanKinds[span.SpanKind()],
+		Timestamp:     span.StartTime().UnixMicro(),
+		Duration:      max(span.EndTime().Sub(span.StartTime()).Microseconds(), 1),
+		LocalEndpoint: e.localEndpoint,
+		Tags:          make(map[string]string),
+	}
+
+	if parent := span.Parent(); parent.IsValid() {
+		s.ParentID = parent.SpanID().String()
+	}
+
+	for _, attr := range span.Resource().Attributes() {
+		if attr.Key == "service.name" {
+			if len(e.localEndpoint.ServiceName) == 0 {
+				s.LocalEndpoint = &Endpoint{ServiceName: attr.Value.AsString()}
+			}
+
+			continue
+		}
+
+		s.Tags[string(attr.Key)] = attr.Value.Emit()
+	}
+
+	for _, attr := range span.Attributes() {
+		s.Tags[string(attr.Key)] = attr.Value.Emit()
+	}
+
+	s.RemoteEndpoint = remoteEndpoint(span.Attributes())
+
+	switch status := span.Status(); status.Code {
+	case otelcodes.Error:
+		s.Tags["otel.status_code"] = "ERROR"
+
+		// Zipkin UI marks spans with an "error" tag as failed
+		s.Tags["error"] = status.Description
+		if len(status.Description) == 0 {
+			s.Tags["error"] = "true"
+		}
+	case otelcodes.Ok:
+		s.Tags["otel.status_code"] = "OK"
+	}
+
+	for _, event := range span.Events() {
+		s.Annotations = append(s.Annotations, Annotation{
+			Timestamp: event.Time.UnixMicro(),
+			Value:     annotationValue(event),
+		})
+	}
+
+	if len(s.Tags) == 0 {
+		s.Tags = nil
+	}
+
+	return s
+}
+
+func annotationValue(event sdktrace.Event) string {
+	if len(event.Attributes) == 0 {
+		return event.Name
+	}
+
+	attributes := make(map[string]any, len(event.Attributes))
+	for _, attr := range event.Attributes {
+		attributes[string(attr.Key)] = attr.Value.AsInterface()
+	}
+
+	encoded, err := json.Marshal(attributes)
+	if err != nil {
+		return event.Name
+	}
+
+	return event.Name + ": " + string(encoded)
+}
+
+// remoteEndpoint is built from peer attributes of client and server spans.
+func remoteEndpoint(attributes []otelattribute.KeyValue) *Endpoint {
+	var (
+		endpoint Endpoint
+		found    bool
+	)
+
+	for _, attr := range attributes {
+		switch attr.Key {
+		case "peer.service":
+			endpoint.ServiceName = attr.Value.AsString()
+		case "server.address", "net.peer.name", "network.peer.address", "net.peer.ip":
+			ip := net.ParseIP(attr.Value.AsString())
+			switch {
+			case ip == nil:
+				if len(endpoint.ServiceName) == 0 {
+					endpoint.ServiceName = attr.Value.AsString()
+				}
+			case ip.To4() != nil:
+				endpoint.IPv4 = ip.String()
+			default:
+				endpoint.IPv6 = ip.String()
+			}
+		case "server.port", "net.peer.port", "network.peer.port":
+			if attr.Value.Type() == otelattribute.INT64 {
+				endpoint.Port = int(attr.Value.AsInt64())
+			} else if port, err := strconv.Atoi(attr.Value.Emit()); err == nil {
+				endpoint.Port = port
+			}
+		default:
+			continue
+		}
+
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+
+	return &endpoint
+}
@@ -0,0 +1,124 @@
+package zipkin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+func newZipkinStub(t *testing.T, urlPath string) (*httptest.Server, func() map[string]Span) {
+	var (
+		mux   sync.Mutex
+		spans = make(map[string]Span)
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, urlPath, r.URL.Path)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+
+		var payload []Span
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		mux.Lock()
+		for _, span := range payload {
+			spans[span.Name] = span
+		}
+		mux.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() map[string]Span {
+		mux.Lock()
+		defer mux.Unlock()
+
+		return spans
+	}
+}
+
+func parentFunc(ctx context.Context) {
+	defer coretracer.TraceWithName(&ctx, "parentFunc", coretracer.NewTags(map[string]any{
+		"svc":          "myService",
+		"block_height": 42,
+	}))()
+
+	childFunc(ctx)
+}
+
+func childFunc(ctx context.Context) {
+	defer coretracer.TraceWithName(&ctx, "childFunc", coretracer.NewTags(map[string]any{
+		"peer.service": "postgres",
+		"server.port":  5432,
+	}))()
+
+	coretracer.TraceError(ctx, errors.New("boom"))
+}
+
+func TestExporter_SendsZipkinSpans(t *testing.T) {
+	for _, urlPath := range []string{"", "/zipkin/api/v2/spans"} {
+		expectedPath := urlPath
+		if len(expectedPath) == 0 {
+			expectedPath = defaultURLPath
+		}
+
+		srv, zipkinSpans := newZipkinStub(t, expectedPath)
+
+		coretracer.Enable(&coretracer.Config{
+			ServiceName:      "zipkin-test",
+			EnvName:          "staging",
+			CollectorDSN:     strings.TrimPrefix(srv.URL, "http://"),
+			CollectorURLPath: urlPath,
+			CollectorHeaders: map[string]string{"X-Api-Key": "secret"},
+		}, InitExporter)
+
+		parentFunc(context.Background())
+		coretracer.Close()
+
+		spans := zipkinSpans()
+		require.Len(t, spans, 2)
+
+		parent, child := spans["parentFunc"], spans["childFunc"]
+		require.Len(t, parent.TraceID, 32)
+		require.Len(t, parent.ID, 16)
+		require.Empty(t, parent.ParentID)
+		require.Empty(t, parent.Kind, "Expected internal spans to have no kind")
+		require.Positive(t, parent.Timestamp)
+		require.Positive(t, parent.Duration)
+		require.Equal(t, &Endpoint{ServiceName: "zipkin-test"}, parent.LocalEndpoint)
+		require.Nil(t, parent.RemoteEndpoint)
+		require.Equal(t, "myService", parent.Tags["svc"])
+		require.Equal(t, "42", parent.Tags["block_height"])
+		require.Equal(t, "staging", parent.Tags["deployment.environment"])
+		require.Equal(t, "OK", parent.Tags["otel.status_code"])
+		require.NotContains(t, parent.Tags, "error")
+
+		require.Equal(t, parent.TraceID, child.TraceID)
+		require.Equal(t, parent.ID, child.ParentID)
+		require.Equal(t, &Endpoint{ServiceName: "postgres", Port: 5432}, child.RemoteEndpoint)
+		require.Equal(t, "boom", child.Tags["error"])
+		require.Equal(t, "ERROR", child.Tags["otel.status_code"])
+		require.Len(t, child.Annotations, 1)
+		require.True(t, strings.HasPrefix(child.Annotations[0].Value, "exception: "))
+		require.Contains(t, child.Annotations[0].Value, `"exception.message":"boom"`)
+	}
+}
+
+func TestExporter_FailsAfterShutdown(t *testing.T) {
+	exporter := NewExporter(&coretracer.Config{})
+	require.NoError(t, exporter.Shutdown(context.Background()))
+
+	spans := tracetest.SpanStubs{{Name: "late"}}.Snapshots()
+	require.ErrorIs(t, exporter.ExportSpans(context.Background(), spans), errShutdown, "Expected spans after shutdown to fail")
+}
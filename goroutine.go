@@ -0,0 +1,85 @@
+package coretracer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+// Go runs fn in a new goroutine, within a child span of the span in ctx. The error returned by fn
+// is reported with TraceError, and so are panics, recovered with their stack instead of crashing
+// the process:
+//
+//	coretracer.Go(ctx, "notifySubscribers", func(ctx context.Context) error {
+//		return notifier.Notify(ctx, order)
+//	}, coretracer.NewTag("order_id", order.ID))
+//
+// The goroutine shares ctx with the caller, so it is canceled along with the caller's work.
+// Use GoDetached for work that outlives the caller.
+func Go(ctx context.Context, name string, fn func(ctx context.Context) error, tags ...Tags) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	go runTraced(ctx, name, fn, tags)
+}
+
+// GoDetached is Go for fire-and-forget work: fn runs in a new root trace linked to the span in ctx,
+// and its context is not canceled when ctx is. Context values, like the baggage, are kept.
+func GoDetached(ctx context.Context, name string, fn func(ctx context.Context) error, tags ...Tags) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	if spanCtx := oteltracer.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		ctx = context.WithValue(ctx, linkedRootKey{}, spanCtx)
+	}
+
+	go runTraced(ctx, name, fn, tags)
+}
+
+func runTraced(ctx context.Context, name string, fn func(ctx context.Context) error, tags []Tags) {
+	endFn := TraceWithName(&ctx, name, tags...)
+
+	defer func() {
+		if r := recover(); r != nil {
+			// the stack trace of the exception starts at the panic
+			TraceError(ctx, fmt.Errorf("panic: %v", r))
+
+			logger().Error("coretracer: goroutine panicked", "name", name, "panic", r, "stack", string(debug.Stack()))
+		}
+
+		endFn()
+	}()
+
+	if err := fn(ctx); err != nil {
+		TraceError(ctx, err)
+	}
+}
+
+// linkedRootKey marks the context, so the next span started from it is the root of a new trace,
+// linked to the span context stored.
+type linkedRootKey struct{}
+
+func linkedRootFromContext(ctx context.Context) (oteltracer.SpanContext, bool) {
+	spanCtx, ok := ctx.Value(linkedRootKey{}).(oteltracer.SpanContext)
+
+	return spanCtx, ok
+}
+
+// logger returns the logger of the last enabled tracer, the default slog logger before.
+func logger() BasicLogger {
+	tracerMux.RLock()
+	defer tracerMux.RUnlock()
+
+	if config == nil {
+		return slog.Default()
+	}
+
+	return config.Logger
+}
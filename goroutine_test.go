@@ -0,0 +1,155 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	otel "go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func enableInMemory(t *testing.T) *tracetest.InMemoryExporter {
+	t.Cleanup(Disable)
+
+	exporter := tracetest.NewInMemoryExporter()
+
+	Enable(&Config{ServiceName: "goroutines"}, func(cfg *Config) ExporterShutdownFn {
+		traceProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter)))
+		otel.SetTracerProvider(traceProvider)
+
+		return traceProvider.Shutdown
+	})
+
+	return exporter
+}
+
+func waitForSpans(t *testing.T, exporter *tracetest.InMemoryExporter, n int) tracetest.SpanStubs {
+	require.Eventually(t, func() bool {
+		return len(exporter.GetSpans()) >= n
+	}, time.Second, time.Millisecond)
+
+	return exporter.GetSpans()
+}
+
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	require.Failf(t, "span not found", "Expected span %s, got %v", name, spanNames(spans))
+
+	return tracetest.SpanStub{}
+}
+
+func exceptionAttribute(span tracetest.SpanStub, key string) string {
+	for _, event := range span.Events {
+		if event.Name != "exception" {
+			continue
+		}
+
+		for _, attr := range event.Attributes {
+			if string(attr.Key) == key {
+				return attr.Value.AsString()
+			}
+		}
+	}
+
+	return ""
+}
+
+func TestGo(t *testing.T) {
+	exporter := enableInMemory(t)
+
+	ctx := context.Background()
+	endFn := TraceWithName(&ctx, "PlaceOrder")
+
+	Go(ctx, "notifySubscribers", func(ctx context.Context) error {
+		return errors.New("smtp is down")
+	}, NewTag("order_id", 42))
+
+	Go(ctx, "updateStats", func(ctx context.Context) error {
+		var stats map[string]int
+		stats["orders"]++
+
+		return nil
+	})
+
+	Go(ctx, "warmCache", func(ctx context.Context) error {
+		return nil
+	})
+
+	endFn()
+
+	spans := waitForSpans(t, exporter, 4)
+	parent := spanNamed(t, spans, "PlaceOrder")
+
+	notify := spanNamed(t, spans, "notifySubscribers")
+	require.Equal(t, parent.SpanContext.SpanID(), notify.Parent.SpanID())
+	require.Equal(t, otelcodes.Error, notify.Status.Code)
+	require.Equal(t, "smtp is down", exceptionAttribute(notify, "exception.message"))
+	require.Contains(t, notify.Attributes, tagsToAttributes([]Tags{NewTag("order_id", 42)})[0])
+
+	stats := spanNamed(t, spans, "updateStats")
+	require.Equal(t, parent.SpanContext.SpanID(), stats.Parent.SpanID())
+	require.Equal(t, otelcodes.Error, stats.Status.Code)
+	require.Equal(t, "panic: assignment to entry in nil map", exceptionAttribute(stats, "exception.message"))
+	require.Contains(t, exceptionAttribute(stats, "exception.stacktrace"), "coretracer.TestGo.func2",
+		"Expected the stack trace to start at the panic")
+
+	warm := spanNamed(t, spans, "warmCache")
+	require.Equal(t, otelcodes.Ok, warm.Status.Code)
+}
+
+func TestGoDetached(t *testing.T) {
+	exporter := enableInMemory(t)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	endFn := TraceWithName(&ctx, "PlaceOrder")
+
+	ctx, err := WithBaggage(ctx, "tenant", "injective")
+	require.NoError(t, err)
+
+	startC := make(chan struct{})
+	resultC := make(chan error, 1)
+
+	GoDetached(ctx, "archiveOrder", func(ctx context.Context) error {
+		<-startC
+
+		if BaggageValue(ctx, "tenant") != "injective" {
+			resultC <- errors.New("baggage is lost")
+		} else {
+			resultC <- ctx.Err()
+		}
+
+		// continues the new trace
+		defer TraceWithName(&ctx, "writeArchive")()
+
+		return nil
+	})
+
+	endFn()
+	cancelFn()
+	close(startC)
+
+	require.NoError(t, <-resultC, "Expected the detached context not to be canceled with the caller")
+
+	spans := waitForSpans(t, exporter, 3)
+	parent := spanNamed(t, spans, "PlaceOrder")
+	archive := spanNamed(t, spans, "archiveOrder")
+
+	require.False(t, archive.Parent.IsValid(), "Expected a new root span")
+	require.NotEqual(t, parent.SpanContext.TraceID(), archive.SpanContext.TraceID())
+	require.Len(t, archive.Links, 1)
+	require.Equal(t, parent.SpanContext.SpanID(), archive.Links[0].SpanContext.SpanID())
+
+	write := spanNamed(t, spans, "writeArchive")
+	require.Equal(t, archive.SpanContext.SpanID(), write.Parent.SpanID())
+	require.Empty(t, write.Links)
+}
@@ -0,0 +1,347 @@
+package coretracer
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+const (
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	contentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var _ http.Handler = (*MetricsHandler)(nil)
+
+// MetricsHandler serves the span metrics for Prometheus scrapes, without an OpenTelemetry collector.
+// Pass its MeterProvider to SpanMetricsConfig:
+//
+//	metrics := coretracer.NewMetricsHandler()
+//	cfg.SpanMetrics = coretracer.SpanMetricsConfig{Enabled: true, MeterProvider: metrics.MeterProvider()}
+//	http.Handle("/metrics", metrics)
+//
+// Scrapers accepting OpenMetrics get histogram exemplars with the trace ID of a recent sampled span
+// in the bucket, others get the Prometheus text format. Labels are the span name and the tags
+// listed in SpanMetricsConfig.TagKeys, with names sanitized, e.g. span.name becomes span_name.
+type MetricsHandler struct {
+	reader        *sdkmetric.ManualReader
+	meterProvider *sdkmetric.MeterProvider
+}
+
+// NewMetricsHandler creates the handler along with the meter provider it reads.
+func NewMetricsHandler() *MetricsHandler {
+	reader := sdkmetric.NewManualReader()
+
+	return &MetricsHandler{
+		reader: reader,
+		meterProvider: sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(reader),
+			sdkmetric.WithExemplarFilter(exemplar.TraceBasedFilter),
+		),
+	}
+}
+
+// MeterProvider returns the meter provider for SpanMetricsConfig.MeterProvider.
+// Other instruments created from it are served too.
+func (h *MetricsHandler) MeterProvider() metric.MeterProvider {
+	return h.meterProvider
+}
+
+// ServeHTTP implements http.Handler.
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var rm metricdata.ResourceMetrics
+	if err := h.reader.Collect(r.Context(), &rm); err != nil {
+		http.Error(w, fmt.Sprintf("failed to collect metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypePrometheus)
+	}
+
+	bw := bufio.NewWriter(w)
+	writeMetrics(bw, rm, openMetrics)
+	_ = bw.Flush()
+}
+
+// writeMetrics encodes the metrics in the OpenMetrics or Prometheus text format.
+func writeMetrics(w io.Writer, rm metricdata.ResourceMetrics, openMetrics bool) {
+	var metrics []metricdata.Metrics
+	for _, scope := range rm.ScopeMetrics {
+		metrics = append(metrics, scope.Metrics...)
+	}
+
+	slices.SortFunc(metrics, func(a, b metricdata.Metrics) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	for _, m := range metrics {
+		name := metricName(m)
+
+		switch data := m.Data.(type) {
+		case metricdata.Sum[int64]:
+			writeSum(w, name, m.Description, data, openMetrics)
+		case metricdata.Sum[float64]:
+			writeSum(w, name, m.Description, data, openMetrics)
+		case metricdata.Gauge[int64]:
+			writeGauge(w, name, m.Description, data)
+		case metricdata.Gauge[float64]:
+			writeGauge(w, name, m.Description, data)
+		case metricdata.Histogram[int64]:
+			writeHistogram(w, name, m.Description, data, openMetrics)
+		case metricdata.Histogram[float64]:
+			writeHistogram(w, name, m.Description, data, openMetrics)
+		}
+	}
+
+	if openMetrics {
+		fmt.Fprint(w, "# EOF\n")
+	}
+}
+
+// metricName converts the instrument name to the Prometheus conventions,
+// e.g. coretracer.span.duration with unit "s" becomes coretracer_span_duration_seconds.
+func metricName(m metricdata.Metrics) string {
+	name := sanitizeName(m.Name)
+
+	switch m.Unit {
+	case "s":
+		name += "_seconds"
+	case "ms":
+		name += "_milliseconds"
+	case "By":
+		name += "_bytes"
+	}
+
+	return name
+}
+
+func writeSum[N int64 | float64](w io.Writer, name, help string, sum metricdata.Sum[N], openMetrics bool) {
+	if !sum.IsMonotonic {
+		writeHeader(w, name, help, "gauge")
+		for _, point := range sortedPoints(sum.DataPoints) {
+			writeSample(w, name, labels(point.Attributes), float64(point.Value))
+		}
+
+		return
+	}
+
+	// OpenMetrics names the family without the suffix of its samples
+	family := name
+	if !openMetrics {
+		family += "_total"
+	}
+
+	writeHeader(w, family, help, "counter")
+
+	for _, point := range sortedPoints(sum.DataPoints) {
+		writeSample(w, name+"_total", labels(point.Attributes), float64(point.Value))
+	}
+}
+
+func writeGauge[N int64 | float64](w io.Writer, name, help string, gauge metricdata.Gauge[N]) {
+	writeHeader(w, name, help, "gauge")
+
+	for _, point := range sortedPoints(gauge.DataPoints) {
+		writeSample(w, name, labels(point.Attributes), float64(point.Value))
+	}
+}
+
+func writeHistogram[N int64 | float64](w io.Writer, name, help string, histogram metricdata.Histogram[N], openMetrics bool) {
+	writeHeader(w, name, help, "histogram")
+
+	points := histogram.DataPoints
+	slices.SortFunc(points, func(a, b metricdata.HistogramDataPoint[N]) int {
+		return cmp.Compare(a.Attributes.Encoded(labelEncoder), b.Attributes.Encoded(labelEncoder))
+	})
+
+	for _, point := range points {
+		pointLabels := labels(point.Attributes)
+
+		var exemplars []*metricdata.Exemplar[N]
+		if openMetrics {
+			exemplars = bucketExemplars(point)
+		}
+
+		var cumulative uint64
+		for i, count := range point.BucketCounts {
+			cumulative += count
+
+			le := math.Inf(1)
+			if i < len(point.Bounds) {
+				le = point.Bounds[i]
+			}
+
+			bucketLabels := append(slices.Clip(pointLabels), label{name: "le", value: formatFloat(le)})
+			writeSampleLabels(w, name+"_bucket", bucketLabels)
+			fmt.Fprintf(w, " %d", cumulative)
+
+			if i < len(exemplars) && exemplars[i] != nil {
+				writeExemplar(w, exemplars[i])
+			}
+
+			fmt.Fprint(w, "\n")
+		}
+
+		writeSample(w, name+"_sum", pointLabels, float64(point.Sum))
+		writeSample(w, name+"_count", pointLabels, float64(point.Count))
+	}
+}
+
+// bucketExemplars puts the latest exemplar with a trace ID into the bucket of its value.
+func bucketExemplars[N int64 | float64](point metricdata.HistogramDataPoint[N]) []*metricdata.Exemplar[N] {
+	exemplars := make([]*metricdata.Exemplar[N], len(point.BucketCounts))
+
+	for i := range point.Exemplars {
+		e := &point.Exemplars[i]
+		if len(e.TraceID) == 0 {
+			continue
+		}
+
+		bucket, _ := slices.BinarySearch(point.Bounds, float64(e.Value))
+		if bucket >= len(exemplars) {
+			continue
+		}
+
+		if prev := exemplars[bucket]; prev == nil || e.Time.After(prev.Time) {
+			exemplars[bucket] = e
+		}
+	}
+
+	return exemplars
+}
+
+func writeExemplar[N int64 | float64](w io.Writer, e *metricdata.Exemplar[N]) {
+	fmt.Fprintf(w, " # {trace_id=\"%s\"} %s %s",
+		hex.EncodeToString(e.TraceID),
+		formatFloat(float64(e.Value)),
+		formatTimestamp(e.Time),
+	)
+}
+
+func writeHeader(w io.Writer, name, help, metricType string) {
+	if len(help) > 0 {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	}
+
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+func writeSample(w io.Writer, name string, sampleLabels []label, value float64) {
+	writeSampleLabels(w, name, sampleLabels)
+	fmt.Fprintf(w, " %s\n", formatFloat(value))
+}
+
+func writeSampleLabels(w io.Writer, name string, sampleLabels []label) {
+	fmt.Fprint(w, name)
+
+	if len(sampleLabels) == 0 {
+		return
+	}
+
+	fmt.Fprint(w, "{")
+	for i, l := range sampleLabels {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+
+		fmt.Fprintf(w, "%s=\"%s\"", l.name, escapeLabelValue(l.value))
+	}
+	fmt.Fprint(w, "}")
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+func labels(set otelattribute.Set) []label {
+	result := make([]label, 0, set.Len())
+	for _, attr := range set.ToSlice() {
+		result = append(result, label{
+			name:  sanitizeName(string(attr.Key)),
+			value: attr.Value.Emit(),
+		})
+	}
+
+	return result
+}
+
+var labelEncoder = otelattribute.DefaultEncoder()
+
+func sortedPoints[N int64 | float64](points []metricdata.DataPoint[N]) []metricdata.DataPoint[N] {
+	slices.SortFunc(points, func(a, b metricdata.DataPoint[N]) int {
+		return cmp.Compare(a.Attributes.Encoded(labelEncoder), b.Attributes.Encoded(labelEncoder))
+	})
+
+	return points
+}
+
+// sanitizeName replaces the characters not allowed in metric and label names with underscores.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	return b.String()
+}
+
+var (
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+func formatTimestamp(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
@@ -0,0 +1,92 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func scrape(t *testing.T, handler http.Handler, accept string) (string, string) {
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if len(accept) > 0 {
+		req.Header.Set("Accept", accept)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	return rec.Header().Get("Content-Type"), rec.Body.String()
+}
+
+func TestMetricsHandler(t *testing.T) {
+	metrics := NewMetricsHandler()
+	exporter := tracetest.NewInMemoryExporter()
+
+	shutdownFn, err := InitTraceProvider(&Config{
+		SpanMetrics: SpanMetricsConfig{
+			Enabled:       true,
+			TagKeys:       []string{"chain_id"},
+			MeterProvider: metrics.MeterProvider(),
+		},
+	}, exporter)
+	require.NoError(t, err)
+	defer func() { _ = shutdownFn(context.Background()) }()
+
+	tracer := newOtelTracer(&Config{})
+	defer tracer.Close()
+
+	ctx := context.Background()
+	tracer.TraceWithName(&ctx, "GetBalance", NewTag("chain_id", `injective-"1"`))()
+
+	ctx = context.Background()
+	endFn := tracer.TraceWithName(&ctx, "GetBalance", NewTag("chain_id", `injective-"1"`))
+	tracer.TraceError(ctx, errors.New("account not found"))
+	endFn()
+
+	flushProvider(t)
+
+	var traceIDs []string
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "GetBalance" {
+			traceIDs = append(traceIDs, span.SpanContext.TraceID().String())
+		}
+	}
+	require.Len(t, traceIDs, 2)
+
+	contentType, body := scrape(t, metrics, "application/openmetrics-text;version=1.0.0,text/plain;q=0.5")
+	require.Equal(t, contentTypeOpenMetrics, contentType)
+
+	labels := `{chain_id="injective-\"1\"",span_name="GetBalance"}`
+
+	require.Contains(t, body, "# TYPE coretracer_span_calls counter\n")
+	require.Contains(t, body, "coretracer_span_calls_total"+labels+" 2\n")
+	require.Contains(t, body, "coretracer_span_errors_total"+labels+" 1\n")
+	require.Contains(t, body, "# TYPE coretracer_span_duration_seconds histogram\n")
+	require.Contains(t, body, `coretracer_span_duration_seconds_bucket{chain_id="injective-\"1\"",span_name="GetBalance",le="+Inf"} 2`)
+	require.Contains(t, body, "coretracer_span_duration_seconds_count"+labels+" 2\n")
+	require.True(t, strings.HasSuffix(body, "# EOF\n"))
+
+	hasExemplar := strings.Contains(body, `# {trace_id="`+traceIDs[0]+`"}`) ||
+		strings.Contains(body, `# {trace_id="`+traceIDs[1]+`"}`)
+	require.True(t, hasExemplar, "Expected a bucket exemplar with the trace ID of a span, got:\n%s", body)
+
+	contentType, body = scrape(t, metrics, "")
+	require.Equal(t, contentTypePrometheus, contentType)
+	require.Contains(t, body, "# TYPE coretracer_span_calls_total counter\n")
+	require.Contains(t, body, "coretracer_span_calls_total"+labels+" 2\n")
+	require.NotContains(t, body, "trace_id")
+	require.NotContains(t, body, "# EOF")
+}
+
+func TestSanitizeName(t *testing.T) {
+	require.Equal(t, "span_name", sanitizeName("span.name"))
+	require.Equal(t, "_1st_call", sanitizeName("1st-call"))
+	require.Equal(t, "coretracer:calls", sanitizeName("coretracer:calls"))
+}
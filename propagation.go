@@ -0,0 +1,113 @@
+package coretracer
+
+import (
+	"context"
+	"fmt"
+
+	otel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Propagator names follow the OTEL_PROPAGATORS values of the OpenTelemetry spec.
+const (
+	// PropagatorTraceContext is the W3C trace context, i.e. traceparent and tracestate headers.
+	PropagatorTraceContext = "tracecontext"
+	// PropagatorBaggage is the W3C baggage header.
+	PropagatorBaggage = "baggage"
+	// PropagatorNone keeps the global propagator as is, e.g. when the service registers its own.
+	PropagatorNone = "none"
+)
+
+// Carrier holds the propagated fields, e.g. request headers or message metadata.
+type Carrier = propagation.TextMapCarrier
+
+type (
+	// HeaderCarrier adapts http.Header to Carrier, e.g. coretracer.HeaderCarrier(req.Header).
+	HeaderCarrier = propagation.HeaderCarrier
+	// MapCarrier adapts map[string]string to Carrier, e.g. coretracer.MapCarrier(msg.Metadata).
+	MapCarrier = propagation.MapCarrier
+)
+
+// Inject writes the trace context and baggage of ctx into the carrier, using the propagators
+// registered by Enable, so the remote side can continue the trace:
+//
+//	defer coretracer.Trace(&ctx)()
+//	coretracer.Inject(ctx, coretracer.HeaderCarrier(req.Header))
+func Inject(ctx context.Context, carrier Carrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// Extract returns a copy of ctx with the remote trace context and baggage read from the carrier.
+// Spans started by Trace on the returned context continue the remote trace:
+//
+//	ctx := coretracer.Extract(r.Context(), coretracer.HeaderCarrier(r.Header))
+//	defer coretracer.Trace(&ctx)()
+func Extract(ctx context.Context, carrier Carrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// WithBaggage returns a copy of ctx with the baggage member set, propagated by Inject along with the trace.
+// Baggage is sent with every downstream request, keep it small and don't put secrets there.
+func WithBaggage(ctx context.Context, key, value string) (context.Context, error) {
+	member, err := baggage.NewMemberRaw(key, value)
+	if err != nil {
+		return ctx, fmt.Errorf("coretracer: invalid baggage member: %w", err)
+	}
+
+	b, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx, fmt.Errorf("coretracer: failed to set baggage member: %w", err)
+	}
+
+	return baggage.ContextWithBaggage(ctx, b), nil
+}
+
+// BaggageValue returns the value of the baggage member in ctx, empty if it is not set.
+func BaggageValue(ctx context.Context, key string) string {
+	return baggage.FromContext(ctx).Member(key).Value()
+}
+
+// registerPropagators installs the configured propagators globally, unknown ones are skipped.
+func registerPropagators(cfg *Config) {
+	propagator, err := buildPropagator(cfg.Propagators)
+	if err != nil {
+		cfg.Logger.Warn("coretracer: invalid propagators config", "error", err)
+	}
+
+	if propagator != nil {
+		otel.SetTextMapPropagator(propagator)
+	}
+}
+
+// buildPropagator composes the named propagators, defaulting to trace context and baggage.
+// It returns nil for PropagatorNone, along with the known propagators when some are unknown.
+func buildPropagator(names []string) (propagation.TextMapPropagator, error) {
+	if len(names) == 0 {
+		names = []string{PropagatorTraceContext, PropagatorBaggage}
+	}
+
+	var (
+		propagators []propagation.TextMapPropagator
+		err         error
+	)
+
+	for _, name := range names {
+		switch name {
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case PropagatorNone:
+			return nil, nil
+		default:
+			err = fmt.Errorf("unknown propagator %q", name)
+		}
+	}
+
+	if len(propagators) == 0 {
+		return nil, err
+	}
+
+	return propagation.NewCompositeTextMapPropagator(propagators...), err
+}
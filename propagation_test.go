@@ -0,0 +1,93 @@
+package coretracer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	otel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+func registerTestPropagators(t *testing.T, propagators ...string) {
+	prev := otel.GetTextMapPropagator()
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	registerPropagators(validateConfig(&Config{Propagators: propagators}))
+}
+
+func TestInjectExtract(t *testing.T) {
+	registerTestPropagators(t)
+	tracer, exporter := newSampledTracer(t, SamplingConfig{Sampler: SamplerParentBasedAlwaysOn})
+
+	ctx := context.Background()
+	ctx, err := WithBaggage(ctx, "tenant", "injective")
+	require.NoError(t, err)
+
+	endFn := tracer.TraceWithName(&ctx, "SendOrder")
+	clientSpan := oteltracer.SpanContextFromContext(ctx)
+
+	header := make(http.Header)
+	Inject(ctx, HeaderCarrier(header))
+	endFn()
+
+	require.Equal(t, "00-"+clientSpan.TraceID().String()+"-"+clientSpan.SpanID().String()+"-01", header.Get("traceparent"))
+	require.Equal(t, "tenant=injective", header.Get("baggage"))
+
+	serverCtx := Extract(context.Background(), HeaderCarrier(header))
+	require.Equal(t, "injective", BaggageValue(serverCtx, "tenant"))
+	require.Empty(t, BaggageValue(serverCtx, "missing"))
+
+	tracer.TraceWithName(&serverCtx, "HandleOrder")()
+
+	spans := exporter.GetSpans()
+	require.Equal(t, []string{"SendOrder", "HandleOrder"}, spanNames(spans))
+
+	serverSpan := spans[1]
+	require.Equal(t, clientSpan.TraceID(), serverSpan.SpanContext.TraceID(), "Expected the remote trace to continue")
+	require.Equal(t, clientSpan.SpanID(), serverSpan.Parent.SpanID())
+	require.True(t, serverSpan.Parent.IsRemote())
+
+	carrier := MapCarrier{}
+	Inject(context.Background(), carrier)
+	require.Empty(t, carrier, "Expected nothing injected outside of a span")
+}
+
+func TestInjectExtract_Unsampled(t *testing.T) {
+	registerTestPropagators(t, PropagatorTraceContext)
+	tracer, exporter := newSampledTracer(t, SamplingConfig{Sampler: SamplerParentBasedAlwaysOn})
+
+	carrier := MapCarrier{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		"baggage":     "tenant=injective",
+	}
+
+	ctx := Extract(context.Background(), carrier)
+	require.Empty(t, BaggageValue(ctx, "tenant"), "Expected baggage propagator not to be registered")
+
+	tracer.TraceWithName(&ctx, "HandleOrder")()
+	require.Empty(t, exporter.GetSpans(), "Expected the remote sampling decision to be respected")
+
+	out := MapCarrier{}
+	Inject(ctx, out)
+	require.Regexp(t, "^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-00$", out["traceparent"])
+}
+
+func TestBuildPropagator(t *testing.T) {
+	propagator, err := buildPropagator(nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"traceparent", "tracestate", "baggage"}, propagator.Fields())
+
+	propagator, err = buildPropagator([]string{PropagatorNone})
+	require.NoError(t, err)
+	require.Nil(t, propagator)
+
+	propagator, err = buildPropagator([]string{"b3", PropagatorBaggage})
+	require.EqualError(t, err, `unknown propagator "b3"`)
+	require.Equal(t, propagation.NewCompositeTextMapPropagator(propagation.Baggage{}), propagator)
+
+	_, err = WithBaggage(context.Background(), "", "injective")
+	require.ErrorContains(t, err, "coretracer: invalid baggage member")
+}
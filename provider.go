@@ -0,0 +1,300 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	otel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// SpanExporterFn creates a span exporter from the config. Exporter packages provide
+// one next to their init function, so exporters can be combined by InitMultiExporter.
+type SpanExporterFn func(cfg *Config) (sdktrace.SpanExporter, error)
+
+// WithConfigOverride returns a SpanExporterFn that builds the exporter from a copy of the config
+// modified by overrideFn, e.g. to point one of the destinations to another collector.
+func (fn SpanExporterFn) WithConfigOverride(overrideFn func(cfg *Config)) SpanExporterFn {
+	return func(cfg *Config) (sdktrace.SpanExporter, error) {
+		cfgCopy := *cfg
+		overrideFn(&cfgCopy)
+
+		return fn(&cfgCopy)
+	}
+}
+
+// InitMultiExporter returns an init function for Enable, that feeds the same spans into
+// all the exporters from one provider. Every exporter has its own batching, so a slow
+// destination can't stall the rest. Exporters that fail to initialize are skipped.
+// Exporters built this way are rebuilt by Reconfigure when the collector settings change.
+func InitMultiExporter(exporterFns ...SpanExporterFn) func(cfg *Config) ExporterShutdownFn {
+	return func(cfg *Config) ExporterShutdownFn {
+		cfg = validateConfig(cfg)
+
+		resources, err := newResource(cfg)
+		if err != nil {
+			// the resource still has the config attributes and the rest of the detected ones
+			cfg.Logger.Warn("coretracer: failed to detect some resource attributes", "error", err)
+		}
+
+		exporters := make([]sdktrace.SpanExporter, 0, len(exporterFns))
+		createdFns := make([]SpanExporterFn, 0, len(exporterFns))
+
+		for i, exporterFn := range exporterFns {
+			exporter, err := exporterFn(cfg)
+			if err != nil {
+				cfg.Logger.Warn("coretracer: multi exporter: failed to create exporter", "index", i, "error", err)
+				continue
+			}
+
+			exporters = append(exporters, exporter)
+			createdFns = append(createdFns, exporterFn)
+		}
+
+		if len(exporters) == 0 {
+			cfg.Logger.Warn("coretracer: multi exporter: no exporters available")
+			return emptyShutdownFn()
+		}
+
+		return installTraceProvider(cfg, resources, exporters, createdFns)
+	}
+}
+
+// ExporterPinger is implemented by span exporters that can check whether their destination
+// is reachable. InitExporters pings them, so EnableE fails while the collector is down.
+type ExporterPinger interface {
+	Ping(ctx context.Context) error
+}
+
+const pingTimeout = 10 * time.Second
+
+// InitExporters returns an init function for EnableE, that feeds spans into all the exporters,
+// like InitMultiExporter does for Enable. It fails when resource detection fails, or when any of the
+// exporters fails to initialize or to reach its destination, see ExporterPinger.
+func InitExporters(exporterFns ...SpanExporterFn) ExporterInitFn {
+	return func(cfg *Config) (ExporterShutdownFn, error) {
+		cfg = validateConfig(cfg)
+
+		resources, err := newResource(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect resource: %w", err)
+		}
+
+		exporters := make([]sdktrace.SpanExporter, 0, len(exporterFns))
+		for i, exporterFn := range exporterFns {
+			exporter, err := exporterFn(cfg)
+			if err != nil {
+				shutdownExporters(exporters)
+				return nil, fmt.Errorf("failed to create exporter %d: %w", i, err)
+			}
+
+			exporters = append(exporters, exporter)
+
+			if err := pingExporter(exporter); err != nil {
+				shutdownExporters(exporters)
+				return nil, fmt.Errorf("exporter %d can't reach its destination: %w", i, err)
+			}
+		}
+
+		if len(exporters) == 0 {
+			return nil, errors.New("no exporters given")
+		}
+
+		return installTraceProvider(cfg, resources, exporters, exporterFns), nil
+	}
+}
+
+func pingExporter(exporter sdktrace.SpanExporter) error {
+	pinger, ok := exporter.(ExporterPinger)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancelFn()
+
+	return pinger.Ping(ctx)
+}
+
+func shutdownExporters(exporters []sdktrace.SpanExporter) {
+	for _, exporter := range exporters {
+		_ = exporter.Shutdown(context.Background())
+	}
+}
+
+// InitTraceProvider installs the global trace provider that feeds finished spans
+// into the exporters, with the resource and the sampler built from the config.
+// Exporter packages use it to implement their init functions for Enable; called from one,
+// the provider is installed by Enable once the init returns, unless Enable was superseded meanwhile.
+// Reconfigure can't rebuild exporters given ready, prefer InitMultiExporter
+// when the collector settings are expected to change at runtime.
+func InitTraceProvider(cfg *Config, exporters ...sdktrace.SpanExporter) (ExporterShutdownFn, error) {
+	cfg = validateConfig(cfg)
+
+	resources, err := newResource(cfg)
+	if err != nil {
+		// the resource still has the config attributes and the rest of the detected ones
+		cfg.Logger.Warn("coretracer: failed to detect some resource attributes", "error", err)
+	}
+
+	return installTraceProvider(cfg, resources, exporters, nil), nil
+}
+
+// tracePipeline is the part of the installed trace provider that Reconfigure changes in place.
+type tracePipeline struct {
+	sampler    *swappableSampler
+	processors []*batchSpanProcessor
+	// exporterFns rebuild the exporters of the processors, nil when the exporters were given ready.
+	exporterFns []SpanExporterFn
+}
+
+// pendingInstalls holds the installation of the trace providers built by init functions run by Enable,
+// by the config given to the init. Enable installs them with the tracer lock held once the init
+// returns, unless it was superseded meanwhile, so a late init never replaces the live provider.
+var pendingInstalls sync.Map // map[*Config]*func()
+
+// runInit runs the init function, returning the installation of the trace provider it built instead
+// of installing it. installFn is a no-op when the init installed its own provider.
+func runInit(cfg *Config, exporterInitFn ExporterInitFn) (shutdownFn ExporterShutdownFn, installFn func(), err error) {
+	slot := new(func())
+
+	pendingInstalls.Store(cfg, slot)
+	defer pendingInstalls.Delete(cfg)
+
+	shutdownFn, err = exporterInitFn(cfg)
+
+	if *slot == nil {
+		return shutdownFn, func() {}, err
+	}
+
+	return shutdownFn, *slot, err
+}
+
+// activePipeline is the pipeline of the installed trace provider, until it's shut down.
+var activePipeline atomic.Pointer[tracePipeline]
+
+// buildExporters builds new exporters from the config, one per processor. Exporters that were built
+// are shut down when any fails, so a failure leaves the pipeline intact.
+func (p *tracePipeline) buildExporters(cfg *Config) ([]sdktrace.SpanExporter, error) {
+	exporters := make([]sdktrace.SpanExporter, 0, len(p.exporterFns))
+	for i, exporterFn := range p.exporterFns {
+		exporter, err := exporterFn(cfg)
+		if err != nil {
+			shutdownExporters(exporters)
+			return nil, fmt.Errorf("failed to create exporter %d: %w", i, err)
+		}
+
+		exporters = append(exporters, exporter)
+	}
+
+	return exporters, nil
+}
+
+// swapExporters hands the exporters over to the processors, without waiting for exports in progress.
+// Spans queued meanwhile go to the new exporters, the previous ones are shut down by the processors.
+func (p *tracePipeline) swapExporters(exporters []sdktrace.SpanExporter) {
+	for i, processor := range p.processors {
+		processor.swapExporter(exporters[i])
+	}
+}
+
+func installTraceProvider(
+	cfg *Config,
+	resources *resource.Resource,
+	exporters []sdktrace.SpanExporter,
+	exporterFns []SpanExporterFn,
+) ExporterShutdownFn {
+	pipeline := &tracePipeline{
+		sampler:     newSwappableSampler(cfg.Sampler(), cfg.SpanMetrics.Enabled),
+		processors:  make([]*batchSpanProcessor, 0, len(exporters)),
+		exporterFns: exporterFns,
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(pipeline.sampler),
+		sdktrace.WithResource(resources),
+		sdktrace.WithSpanProcessor(statsSpanProcessor{}),
+	}
+
+	if cfg.SpanMetrics.Enabled {
+		metricsProcessor, err := newSpanMetricsProcessor(cfg.SpanMetrics)
+		if err != nil {
+			cfg.Logger.Warn("coretracer: failed to set up span metrics", "error", err)
+		} else {
+			opts = append(opts, sdktrace.WithSpanProcessor(metricsProcessor))
+		}
+	}
+
+	batchOpts := cfg.batchOptions()
+
+	batchProcessors := make([]sdktrace.SpanProcessor, 0, len(exporters))
+	for _, exporter := range exporters {
+		processor := newBatchSpanProcessor(exporter, batchOpts)
+		pipeline.processors = append(pipeline.processors, processor)
+		batchProcessors = append(batchProcessors, processor)
+	}
+
+	// tail sampling feeds the batch processors with the kept traces only
+	var tailSampler *tailSamplingProcessor
+
+	if cfg.TailSampling.Enabled {
+		tailSampler = newTailSamplingProcessor(cfg.tailSamplingOptions(), batchProcessors...)
+		opts = append(opts, sdktrace.WithSpanProcessor(tailSampler))
+	} else {
+		for _, processor := range batchProcessors {
+			opts = append(opts, sdktrace.WithSpanProcessor(processor))
+		}
+	}
+
+	traceProvider := sdktrace.NewTracerProvider(opts...)
+
+	installFn := func() {
+		otel.SetTracerProvider(traceProvider)
+		activePipeline.Store(pipeline)
+	}
+
+	if slot, ok := pendingInstalls.Load(cfg); ok {
+		*slot.(*func()) = installFn
+	} else {
+		installFn()
+	}
+
+	return func(ctx context.Context) error {
+		activePipeline.CompareAndSwap(pipeline, nil)
+
+		// buffered traces are decided before the queues are drained
+		if tailSampler != nil {
+			_ = tailSampler.Shutdown(ctx)
+		}
+
+		processors := pipeline.processors
+
+		// processors are shut down concurrently, so a slow destination
+		// doesn't eat the shutdown deadline of the others.
+		errs := make([]error, len(processors))
+		wg := new(sync.WaitGroup)
+
+		for i, processor := range processors {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = processor.Shutdown(ctx)
+			}()
+		}
+
+		wg.Wait()
+
+		errs = append(errs, traceProvider.Shutdown(ctx))
+
+		return errors.Join(errs...)
+	}
+}
+
+func emptyShutdownFn() ExporterShutdownFn {
+	return func(ctx context.Context) error { return nil }
+}
@@ -0,0 +1,117 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// blockingExporter never finishes an export until released.
+type blockingExporter struct {
+	releaseC chan struct{}
+}
+
+func (e *blockingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	select {
+	case <-e.releaseC:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *blockingExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// shutdownRecorder wraps an exporter to record its shutdown.
+type shutdownRecorder struct {
+	sdktrace.SpanExporter
+	called atomic.Bool
+}
+
+func (e *shutdownRecorder) Shutdown(ctx context.Context) error {
+	e.called.Store(true)
+	return e.SpanExporter.Shutdown(ctx)
+}
+
+func exporterFn(exporter sdktrace.SpanExporter) SpanExporterFn {
+	return func(cfg *Config) (sdktrace.SpanExporter, error) {
+		return exporter, nil
+	}
+}
+
+func TestInitMultiExporter_FansOutSpans(t *testing.T) {
+	first := tracetest.NewInMemoryExporter()
+	second := tracetest.NewInMemoryExporter()
+
+	shutdownFn := InitMultiExporter(
+		exporterFn(first),
+		func(cfg *Config) (sdktrace.SpanExporter, error) {
+			return nil, errors.New("destination is misconfigured")
+		},
+		exporterFn(second),
+	)(&Config{ServiceName: "multi"})
+
+	tracer := newOtelTracer(&Config{})
+	defer tracer.Close()
+
+	ctx := context.Background()
+	tracer.TraceWithName(&ctx, "fan-out")()
+
+	// in-memory exporters drop spans on shutdown, so flush first
+	require.NoError(t, otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background()))
+	require.Equal(t, []string{"fan-out"}, spanNames(first.GetSpans()))
+	require.Equal(t, []string{"fan-out"}, spanNames(second.GetSpans()))
+
+	require.NoError(t, shutdownFn(context.Background()))
+}
+
+func TestInitMultiExporter_SlowDestinationDoesNotStallOthers(t *testing.T) {
+	fast := tracetest.NewInMemoryExporter()
+	slow := &blockingExporter{releaseC: make(chan struct{})}
+	defer close(slow.releaseC)
+
+	fastShutdown := &shutdownRecorder{SpanExporter: fast}
+	shutdownFn := InitMultiExporter(exporterFn(slow), exporterFn(fastShutdown))(&Config{})
+
+	tracer := newOtelTracer(&Config{})
+	defer tracer.Close()
+
+	for i := 0; i < 3; i++ {
+		ctx := context.Background()
+		tracer.TraceWithName(&ctx, "span")()
+	}
+
+	shutdownCtx, cancelFn := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelFn()
+
+	err := shutdownFn(shutdownCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.True(t, fastShutdown.called.Load(), "Expected fast exporter to be shut down despite the slow one")
+}
+
+func TestSpanExporterFn_WithConfigOverride(t *testing.T) {
+	var seenDSN string
+
+	fn := SpanExporterFn(func(cfg *Config) (sdktrace.SpanExporter, error) {
+		seenDSN = cfg.CollectorDSN
+		return tracetest.NewInMemoryExporter(), nil
+	}).WithConfigOverride(func(cfg *Config) {
+		cfg.CollectorDSN = "new-collector:4317"
+	})
+
+	cfg := &Config{CollectorDSN: "old-collector:4317"}
+	_, err := fn(cfg)
+	require.NoError(t, err)
+
+	require.Equal(t, "new-collector:4317", seenDSN)
+	require.Equal(t, "old-collector:4317", cfg.CollectorDSN, "Expected original config to stay intact")
+}
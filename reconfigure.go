@@ -0,0 +1,207 @@
+package coretracer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	otel "go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// reconfigureMux serializes Reconfigure calls, that hold tracerMux only to apply the changes.
+var reconfigureMux sync.Mutex
+
+// Reconfigure applies the config to the running tracer, keeping the tracer and its trace provider,
+// so spans in flight are not lost and callers holding DefaultTracer() are not affected. It changes:
+//   - sampling and the stuck function watchdog, for spans started afterwards;
+//   - the logger and the propagators;
+//   - the collector settings, i.e. Collector* fields, by rebuilding the exporters created
+//     by InitMultiExporter or InitExporters. Queued spans are exported by the new exporters.
+//
+// The service identity, resource, queue, tail sampling and span metrics settings take effect
+// after Close and Enable only, changing them is reported as a warning. So are the collector
+// settings of exporters given ready to InitTraceProvider, and sampling when the init function
+// installed its own trace provider.
+// Every changed field is logged through cfg.Logger, without header values and PEM contents.
+// Nothing is applied when an error is returned.
+func Reconfigure(cfg *Config) error {
+	reconfigureMux.Lock()
+	defer reconfigureMux.Unlock()
+
+	tracerMux.RLock()
+	t, ok := tracer.(*otelTracer)
+	prev := config
+	tracerMux.RUnlock()
+
+	if !ok {
+		return errors.New("coretracer: tracer is not enabled")
+	}
+
+	cfg = validateConfig(cfg)
+
+	sampler, err := cfg.Sampling.build()
+	if err != nil {
+		return fmt.Errorf("coretracer: invalid sampling config: %w", err)
+	}
+
+	propagator, err := buildPropagator(cfg.Propagators)
+	if err != nil {
+		return fmt.Errorf("coretracer: invalid propagators config: %w", err)
+	}
+
+	changes := diffConfigs(prev, cfg)
+	pipeline := activePipeline.Load()
+
+	var (
+		notApplied []string
+		exporters  []sdktrace.SpanExporter
+	)
+
+	if slices.ContainsFunc(changes, configChange.isCollector) {
+		if pipeline == nil || pipeline.exporterFns == nil {
+			notApplied = append(notApplied, "Collector*")
+		} else if exporters, err = pipeline.buildExporters(cfg); err != nil {
+			// built without the lock, exporters may dial or ping the collector
+			return fmt.Errorf("coretracer: failed to reconfigure exporters: %w", err)
+		}
+	}
+
+	tracerMux.Lock()
+	defer tracerMux.Unlock()
+
+	// disabled or enabled again while the exporters were built
+	if tracer != Tracer(t) || activePipeline.Load() != pipeline {
+		shutdownExporters(exporters)
+		return errors.New("coretracer: tracer was enabled again or disabled during Reconfigure")
+	}
+
+	if exporters != nil {
+		pipeline.swapExporters(exporters)
+	}
+
+	if pipeline != nil {
+		pipeline.sampler.swap(sampler)
+	} else if slices.ContainsFunc(changes, configChange.isSampling) {
+		notApplied = append(notApplied, "Sampling")
+	}
+
+	if propagator != nil {
+		otel.SetTextMapPropagator(propagator)
+	}
+
+	setErrorHandler(cfg)
+	t.reconfigure(cfg)
+	config = cfg.clone()
+
+	for _, change := range changes {
+		cfg.Logger.Info("coretracer: config changed", "field", change.field, "old", change.old, "new", change.new)
+
+		if change.needsRestart() {
+			notApplied = append(notApplied, change.field)
+		}
+	}
+
+	if len(notApplied) > 0 {
+		cfg.Logger.Warn("coretracer: some config changes take effect after Close and Enable", "fields", notApplied)
+	}
+
+	return nil
+}
+
+// configChange is a changed config field, with the values safe to be logged.
+type configChange struct {
+	field    string
+	old, new any
+}
+
+// restartFields can't be changed on a running tracer: they are baked into the resource and the queues.
+var restartFields = []string{
+	"EnvName",
+	"ServiceName",
+	"ServiceVersion",
+	"ClusterID",
+	"ResourceAttributes",
+	"ResourceDetectors",
+	"Queue.",
+	"TailSampling.",
+	"SpanMetrics.",
+}
+
+func (c configChange) needsRestart() bool {
+	return slices.ContainsFunc(restartFields, func(field string) bool {
+		return c.field == field || strings.HasPrefix(c.field, field)
+	})
+}
+
+func (c configChange) isCollector() bool {
+	return strings.HasPrefix(c.field, "Collector")
+}
+
+func (c configChange) isSampling() bool {
+	return strings.HasPrefix(c.field, "Sampling.")
+}
+
+// diffConfigs lists the changed fields, nested structs field by field, e.g. "Sampling.Ratio".
+// Interfaces like the logger are not compared, detectors are compared by count only.
+func diffConfigs(prev, next *Config) []configChange {
+	var changes []configChange
+
+	if len(prev.ResourceDetectors) != len(next.ResourceDetectors) {
+		changes = append(changes, configChange{
+			field: "ResourceDetectors",
+			old:   len(prev.ResourceDetectors),
+			new:   len(next.ResourceDetectors),
+		})
+	}
+
+	return diffStructs("", reflect.ValueOf(*prev), reflect.ValueOf(*next), changes)
+}
+
+func diffStructs(prefix string, prev, next reflect.Value, changes []configChange) []configChange {
+	for i := range prev.NumField() {
+		field := prefix + prev.Type().Field(i).Name
+		prevValue, nextValue := prev.Field(i), next.Field(i)
+
+		switch {
+		case prevValue.Kind() == reflect.Interface || field == "ResourceDetectors":
+			continue
+		case prevValue.Kind() == reflect.Struct:
+			changes = diffStructs(field+".", prevValue, nextValue, changes)
+		case !reflect.DeepEqual(prevValue.Interface(), nextValue.Interface()):
+			changes = append(changes, configChange{
+				field: field,
+				old:   loggableValue(field, prevValue),
+				new:   loggableValue(field, nextValue),
+			})
+		}
+	}
+
+	return changes
+}
+
+// loggableValue hides secrets: only header names are logged, and whether PEM contents are set.
+func loggableValue(field string, value reflect.Value) any {
+	switch {
+	case field == "CollectorHeaders":
+		names := make([]string, 0, value.Len())
+		for _, key := range value.MapKeys() {
+			names = append(names, key.String())
+		}
+
+		slices.Sort(names)
+
+		return names
+	case strings.HasSuffix(field, "PEM"):
+		if value.Len() == 0 {
+			return ""
+		}
+
+		return "<redacted>"
+	default:
+		return value.Interface()
+	}
+}
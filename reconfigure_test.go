@@ -0,0 +1,172 @@
+package coretracer
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// headerExporter remembers the collector headers it was created with.
+type headerExporter struct {
+	*tracetest.InMemoryExporter
+	headers map[string]string
+}
+
+// rebuildableExporters records every exporter built by its SpanExporterFn.
+type rebuildableExporters struct {
+	mux       sync.Mutex
+	exporters []*headerExporter
+}
+
+func (r *rebuildableExporters) exporterFn(cfg *Config) (sdktrace.SpanExporter, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	exporter := &headerExporter{
+		InMemoryExporter: tracetest.NewInMemoryExporter(),
+		headers:          cfg.CollectorHeaders,
+	}
+	r.exporters = append(r.exporters, exporter)
+
+	return exporter, nil
+}
+
+func (r *rebuildableExporters) last() *headerExporter {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.exporters[len(r.exporters)-1]
+}
+
+func TestReconfigure(t *testing.T) {
+	t.Cleanup(Disable)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	built := new(rebuildableExporters)
+	Enable(&Config{
+		CollectorHeaders: map[string]string{"x-api-key": "old-secret"},
+		Logger:           logger,
+	}, InitMultiExporter(built.exporterFn))
+
+	tracerBefore := DefaultTracer()
+
+	ctx := context.Background()
+	TraceWithName(&ctx, "queued")()
+
+	inFlightCtx := context.Background()
+	endInFlight := TraceWithName(&inFlightCtx, "in-flight")
+
+	err := Reconfigure(&Config{
+		CollectorHeaders:      map[string]string{"x-api-key": "new-secret", "x-tenant": "core"},
+		StuckFunctionWatchdog: true,
+		StuckFunctionTimeout:  time.Minute,
+		Sampling: SamplingConfig{
+			SpanNameRatios: map[string]float64{"unsampled": 0},
+		},
+		Logger: logger,
+	})
+	require.NoError(t, err)
+	require.Same(t, tracerBefore, DefaultTracer(), "Expected the tracer to be kept")
+
+	endInFlight()
+
+	// a root span, the name override doesn't apply to children
+	unsampledCtx := context.Background()
+	TraceWithName(&unsampledCtx, "unsampled")()
+	flushProvider(t)
+
+	require.Len(t, built.exporters, 2)
+
+	exporter := built.last()
+	require.Equal(t, map[string]string{"x-api-key": "new-secret", "x-tenant": "core"}, exporter.headers)
+	require.ElementsMatch(t, []string{"queued", "in-flight"}, spanNames(exporter.GetSpans()),
+		"Expected queued and in-flight spans to be exported by the new exporter")
+
+	require.True(t, DefaultTracer().(*otelTracer).config.Load().StuckFunctionWatchdog)
+
+	require.Contains(t, logs.String(), `msg="coretracer: config changed" field=CollectorHeaders old=[x-api-key] new="[x-api-key x-tenant]"`)
+	require.Contains(t, logs.String(), `field=StuckFunctionTimeout old=5m0s new=1m0s`)
+	require.Contains(t, logs.String(), `field=Sampling.SpanNameRatios`)
+	require.NotContains(t, logs.String(), "secret")
+}
+
+func TestReconfigure_SameConfig(t *testing.T) {
+	t.Cleanup(Disable)
+
+	var logs bytes.Buffer
+
+	built := new(rebuildableExporters)
+	cfg := &Config{
+		CollectorHeaders: map[string]string{"x-api-key": "old-secret"},
+		Logger:           slog.New(slog.NewTextHandler(&logs, nil)),
+	}
+	Enable(cfg, InitMultiExporter(built.exporterFn))
+
+	cfg.CollectorHeaders["x-tenant"] = "core"
+	cfg.Sampling.SpanNameRatios = map[string]float64{"unsampled": 0}
+
+	require.NoError(t, Reconfigure(cfg))
+	require.Len(t, built.exporters, 2, "Expected the exporters to be rebuilt")
+	require.Contains(t, logs.String(), `field=CollectorHeaders old=[x-api-key] new="[x-api-key x-tenant]"`)
+	require.Contains(t, logs.String(), `field=Sampling.SpanNameRatios`)
+
+	logs.Reset()
+	cfg.Sampling.SpanNameRatios["unsampled"] = 1
+
+	require.NoError(t, Reconfigure(cfg))
+	require.Contains(t, logs.String(), `field=Sampling.SpanNameRatios`)
+}
+
+func TestReconfigure_KeepsConfigOnError(t *testing.T) {
+	t.Cleanup(Disable)
+	Disable()
+
+	require.EqualError(t, Reconfigure(&Config{}), "coretracer: tracer is not enabled")
+
+	exporter := tracetest.NewInMemoryExporter()
+	Enable(&Config{}, InitMultiExporter(exporterFn(exporter)))
+
+	err := Reconfigure(&Config{Sampling: SamplingConfig{Sampler: "sometimes"}})
+	require.ErrorContains(t, err, "invalid sampling config")
+
+	ctx := context.Background()
+	TraceWithName(&ctx, "sampled")()
+	flushProvider(t)
+
+	require.Equal(t, []string{"sampled"}, spanNames(exporter.GetSpans()))
+}
+
+func TestDiffConfigs(t *testing.T) {
+	prev := validateConfig(&Config{
+		ServiceName:  "api",
+		CollectorTLS: TLSConfig{KeyPEM: []byte("old key")},
+		Queue:        QueueConfig{Size: 10},
+	})
+
+	next := validateConfig(&Config{
+		ServiceName:  "api",
+		CollectorTLS: TLSConfig{KeyPEM: []byte("new key"), ServerName: "collector"},
+		Queue:        QueueConfig{Size: 20},
+		Logger:       slog.New(slog.DiscardHandler),
+	})
+
+	changes := diffConfigs(prev, next)
+	require.Equal(t, []configChange{
+		{field: "CollectorTLS.KeyPEM", old: "<redacted>", new: "<redacted>"},
+		{field: "CollectorTLS.ServerName", old: "", new: "collector"},
+		{field: "Queue.Size", old: 10, new: 20},
+	}, changes)
+
+	require.False(t, changes[0].needsRestart())
+	require.True(t, changes[2].needsRestart())
+	require.Empty(t, diffConfigs(prev, prev))
+}
@@ -0,0 +1,169 @@
+package coretracer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// DefaultResourceDetectors returns all the built-in detectors, to be set in Config.ResourceDetectors:
+// host, process, container and Kubernetes.
+func DefaultResourceDetectors() []resource.Detector {
+	return []resource.Detector{
+		HostDetector(),
+		ProcessDetector(),
+		ContainerDetector(),
+		KubernetesDetector(),
+	}
+}
+
+// HostDetector detects host.name and host.arch.
+func HostDetector() resource.Detector {
+	return detectorFunc(func(ctx context.Context) (*resource.Resource, error) {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hostname: %w", err)
+		}
+
+		return resource.NewSchemaless(
+			otelattribute.String("host.name", hostname),
+			otelattribute.String("host.arch", runtime.GOARCH),
+		), nil
+	})
+}
+
+// ProcessDetector detects process.pid, process.executable.name, process.runtime.name
+// and process.runtime.version.
+func ProcessDetector() resource.Detector {
+	return detectorFunc(func(ctx context.Context) (*resource.Resource, error) {
+		return resource.NewSchemaless(
+			otelattribute.Int("process.pid", os.Getpid()),
+			otelattribute.String("process.executable.name", filepath.Base(os.Args[0])),
+			otelattribute.String("process.runtime.name", "go"),
+			otelattribute.String("process.runtime.version", runtime.Version()),
+		), nil
+	})
+}
+
+// cgroupPath is swapped in tests.
+var cgroupPath = "/proc/self/cgroup"
+
+// containerIDRe matches the ID in the last segment of the cgroup path, with the prefixes and
+// suffixes of known runtimes, e.g. "docker-<id>.scope", "cri-containerd-<id>.scope" or just "<id>".
+var containerIDRe = regexp.MustCompile(`^(?:[a-z-]+[-:])?([0-9a-f]{64})(?:\.scope)?$`)
+
+// ContainerDetector detects container.id from /proc/self/cgroup.
+// Detects nothing outside of containers or on systems without cgroups.
+func ContainerDetector() resource.Detector {
+	return detectorFunc(func(ctx context.Context) (*resource.Resource, error) {
+		containerID, err := containerIDFromCgroup(cgroupPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect container ID: %w", err)
+		}
+
+		if len(containerID) == 0 {
+			return resource.Empty(), nil
+		}
+
+		return resource.NewSchemaless(otelattribute.String("container.id", containerID)), nil
+	})
+}
+
+func containerIDFromCgroup(path string) (string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		segment := parts[2][strings.LastIndex(parts[2], "/")+1:]
+		if match := containerIDRe.FindStringSubmatch(segment); match != nil {
+			return match[1], nil
+		}
+	}
+
+	return "", scanner.Err()
+}
+
+// kubernetesEnvVars map resource attributes to the env vars commonly filled
+// via the downward API, the first one set wins.
+var kubernetesEnvVars = []struct {
+	key   string
+	names []string
+}{
+	{"k8s.pod.name", []string{"K8S_POD_NAME", "POD_NAME"}},
+	{"k8s.pod.uid", []string{"K8S_POD_UID", "POD_UID"}},
+	{"k8s.namespace.name", []string{"K8S_NAMESPACE_NAME", "POD_NAMESPACE"}},
+	{"k8s.node.name", []string{"K8S_NODE_NAME", "NODE_NAME"}},
+}
+
+// KubernetesDetector detects k8s.pod.name, k8s.pod.uid, k8s.namespace.name and k8s.node.name
+// from env vars exposed via the downward API, i.e. K8S_POD_NAME or POD_NAME, K8S_POD_UID or POD_UID,
+// K8S_NAMESPACE_NAME or POD_NAMESPACE, K8S_NODE_NAME or NODE_NAME.
+func KubernetesDetector() resource.Detector {
+	return detectorFunc(func(ctx context.Context) (*resource.Resource, error) {
+		var attributes []otelattribute.KeyValue
+
+		for _, attr := range kubernetesEnvVars {
+			for _, name := range attr.names {
+				if value := os.Getenv(name); len(value) > 0 {
+					attributes = append(attributes, otelattribute.String(attr.key, value))
+					break
+				}
+			}
+		}
+
+		return resource.NewSchemaless(attributes...), nil
+	})
+}
+
+type detectorFunc func(ctx context.Context) (*resource.Resource, error)
+
+// Detect implements resource.Detector.
+func (fn detectorFunc) Detect(ctx context.Context) (*resource.Resource, error) {
+	return fn(ctx)
+}
+
+// newResource builds the resource from the detectors and the config. Config fields win
+// over the detected attributes. A failing detector doesn't prevent the rest of the resource
+// from being built, the error is returned along with it.
+func newResource(cfg *Config) (*resource.Resource, error) {
+	attributes := make([]otelattribute.KeyValue, 0, len(cfg.ResourceAttributes)+4)
+	for k, v := range cfg.ResourceAttributes {
+		attributes = append(attributes, otelattribute.String(k, v))
+	}
+
+	// dedicated config fields win over the same keys in ResourceAttributes
+	attributes = append(attributes,
+		otelattribute.String("service.name", cfg.ServiceName),
+		otelattribute.String("service.version", cfg.ServiceVersion),
+		otelattribute.String("deployment.environment", cfg.EnvName),
+		otelattribute.String("deployment.cluster_id", cfg.ClusterID),
+	)
+
+	return resource.New(
+		context.Background(),
+		resource.WithDetectors(cfg.ResourceDetectors...),
+		resource.WithAttributes(attributes...),
+	)
+}
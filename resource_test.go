@@ -0,0 +1,113 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const testContainerID = "3f4a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a"
+
+func resourceValue(t *testing.T, res *resource.Resource, key string) string {
+	value, ok := res.Set().Value(otelattribute.Key(key))
+	require.True(t, ok, "Expected resource attribute %s", key)
+
+	return value.Emit()
+}
+
+func TestContainerIDFromCgroup(t *testing.T) {
+	testCases := map[string]string{
+		"docker v1":         "12:memory:/docker/" + testContainerID + "\n11:cpu:/docker/" + testContainerID,
+		"docker systemd v2": "0::/system.slice/docker-" + testContainerID + ".scope",
+		"kubernetes":        "1:name=systemd:/kubepods/besteffort/pod0c7c1a0e-2b6f-4a6b-9f0c-1a2b3c4d5e6f/" + testContainerID,
+		"containerd":        "0::/kubepods.slice/kubepods-pod123.slice/cri-containerd-" + testContainerID + ".scope",
+		"crio":              "0::/crio-" + testContainerID + ".scope",
+		"host":              "0::/user.slice/user-1000.slice/session-2.scope",
+	}
+
+	dir := t.TempDir()
+
+	for name, cgroup := range testCases {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(cgroup+"\n"), 0o644))
+
+		containerID, err := containerIDFromCgroup(path)
+		require.NoError(t, err)
+
+		if name == "host" {
+			require.Empty(t, containerID)
+		} else {
+			require.Equal(t, testContainerID, containerID, name)
+		}
+	}
+
+	containerID, err := containerIDFromCgroup(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	require.Empty(t, containerID)
+}
+
+func TestNewResource_Detectors(t *testing.T) {
+	cgroupFile := filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(cgroupFile, []byte("0::/docker/"+testContainerID+"\n"), 0o644))
+
+	prevCgroupPath := cgroupPath
+	cgroupPath = cgroupFile
+	t.Cleanup(func() { cgroupPath = prevCgroupPath })
+
+	t.Setenv("K8S_POD_NAME", "")
+	t.Setenv("POD_NAME", "api-7d9f8-x2x4z")
+	t.Setenv("K8S_NAMESPACE_NAME", "core")
+	t.Setenv("POD_NAMESPACE", "ignored")
+
+	customDetector := resource.StringDetector("", "cloud.region", func() (string, error) {
+		return "us-east-1", nil
+	})
+
+	failingDetector := resource.StringDetector("", "cloud.zone", func() (string, error) {
+		return "", errors.New("metadata service is down")
+	})
+
+	res, err := newResource(validateConfig(&Config{
+		ServiceName:        "api",
+		ResourceAttributes: map[string]string{"host.name": "explicit-host"},
+		ResourceDetectors: append(DefaultResourceDetectors(),
+			customDetector,
+			failingDetector,
+		),
+	}))
+	require.ErrorContains(t, err, "metadata service is down")
+
+	hostname, _ := os.Hostname()
+	require.NotEmpty(t, hostname)
+
+	require.Equal(t, "api", resourceValue(t, res, "service.name"))
+	require.Equal(t, "explicit-host", resourceValue(t, res, "host.name"), "Expected config to win over detectors")
+	require.Equal(t, runtime.GOARCH, resourceValue(t, res, "host.arch"))
+	require.Equal(t, runtime.Version(), resourceValue(t, res, "process.runtime.version"))
+	require.NotEmpty(t, resourceValue(t, res, "process.pid"))
+	require.Equal(t, testContainerID, resourceValue(t, res, "container.id"))
+	require.Equal(t, "api-7d9f8-x2x4z", resourceValue(t, res, "k8s.pod.name"))
+	require.Equal(t, "core", resourceValue(t, res, "k8s.namespace.name"))
+	require.Equal(t, "us-east-1", resourceValue(t, res, "cloud.region"))
+
+	_, ok := res.Set().Value("cloud.zone")
+	require.False(t, ok)
+}
+
+func TestNewResource_NoDetectorsByDefault(t *testing.T) {
+	res, err := newResource(validateConfig(&Config{}))
+	require.NoError(t, err)
+
+	_, ok := res.Set().Value("host.name")
+	require.False(t, ok, "Expected detectors to be opt-in")
+
+	_, err = HostDetector().Detect(context.Background())
+	require.NoError(t, err)
+}
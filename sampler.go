@@ -0,0 +1,198 @@
+package coretracer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+// Sampler names follow the OTEL_TRACES_SAMPLER values of the OpenTelemetry spec.
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// SamplingConfig configures head sampling, i.e. the decision made when a span starts.
+// The zero value samples everything.
+type SamplingConfig struct {
+	// Sampler is one of Sampler* constants, defaults to SamplerAlwaysOn.
+	Sampler string
+	// Ratio is the fraction of traces to keep for ratio-based samplers, in [0, 1].
+	Ratio float64
+	// SpanNameRatios overrides the ratio for root spans with the given names,
+	// e.g. {"EndBlocker": 1, "GetBalance": 0.01}. Children with these names
+	// follow their parent, so traces are never broken.
+	SpanNameRatios map[string]float64
+}
+
+// Sampler builds the head sampler to be installed into the trace provider.
+func (c *Config) Sampler() sdktrace.Sampler {
+	if c == nil {
+		c = DefaultConfig()
+	}
+
+	sampler, err := c.Sampling.build()
+	if err != nil {
+		logger := c.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+
+		logger.Warn("coretracer: invalid sampling config, falling back to always_on", "error", err)
+		return sdktrace.AlwaysSample()
+	}
+
+	return sampler
+}
+
+func (s SamplingConfig) build() (sdktrace.Sampler, error) {
+	if s.Ratio < 0 || s.Ratio > 1 {
+		return nil, fmt.Errorf("sampling ratio %v is out of [0, 1] range", s.Ratio)
+	}
+
+	for name, ratio := range s.SpanNameRatios {
+		if ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("sampling ratio %v for span %q is out of [0, 1] range", ratio, name)
+		}
+	}
+
+	var (
+		root        sdktrace.Sampler
+		parentBased bool
+	)
+
+	switch s.Sampler {
+	case "", SamplerAlwaysOn:
+		root = sdktrace.AlwaysSample()
+	case SamplerAlwaysOff:
+		root = sdktrace.NeverSample()
+	case SamplerTraceIDRatio:
+		root = sdktrace.TraceIDRatioBased(s.Ratio)
+	case SamplerParentBasedAlwaysOn:
+		root, parentBased = sdktrace.AlwaysSample(), true
+	case SamplerParentBasedAlwaysOff:
+		root, parentBased = sdktrace.NeverSample(), true
+	case SamplerParentBasedTraceIDRatio:
+		root, parentBased = sdktrace.TraceIDRatioBased(s.Ratio), true
+	default:
+		return nil, fmt.Errorf("unknown sampler %q", s.Sampler)
+	}
+
+	if len(s.SpanNameRatios) > 0 {
+		overrides := make(map[string]sdktrace.Sampler, len(s.SpanNameRatios))
+		for name, ratio := range s.SpanNameRatios {
+			overrides[name] = sdktrace.TraceIDRatioBased(ratio)
+		}
+
+		root = &spanNameSampler{
+			overrides: overrides,
+			fallback:  root,
+		}
+	}
+
+	if parentBased {
+		return sdktrace.ParentBased(root), nil
+	}
+
+	return root, nil
+}
+
+var _ sdktrace.Sampler = (*spanNameSampler)(nil)
+
+// spanNameSampler delegates the decision to a per-name sampler, if there is one.
+type spanNameSampler struct {
+	overrides map[string]sdktrace.Sampler
+	fallback  sdktrace.Sampler
+}
+
+// followParent samples the span only if its parent is sampled.
+var followParent = sdktrace.ParentBased(sdktrace.NeverSample())
+
+// ShouldSample implements sdktrace.Sampler.
+func (s *spanNameSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	sampler, ok := s.overrides[p.Name]
+	if !ok {
+		return s.fallback.ShouldSample(p)
+	}
+
+	// the override would leave a hole in the trace of the parent
+	if oteltracer.SpanContextFromContext(p.ParentContext).IsValid() {
+		return followParent.ShouldSample(p)
+	}
+
+	return sampler.ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler.
+func (s *spanNameSampler) Description() string {
+	return fmt.Sprintf("SpanNameSampler{overrides:%d,fallback:%s}", len(s.overrides), s.fallback.Description())
+}
+
+var _ sdktrace.Sampler = (*swappableSampler)(nil)
+
+// swappableSampler delegates to the sampler set last, so Reconfigure can change
+// sampling without replacing the trace provider.
+type swappableSampler struct {
+	sampler atomic.Pointer[sdktrace.Sampler]
+	// recordDropped turns drop decisions into record-only ones,
+	// so span metrics see the spans that are not exported.
+	recordDropped bool
+}
+
+func newSwappableSampler(sampler sdktrace.Sampler, recordDropped bool) *swappableSampler {
+	s := &swappableSampler{recordDropped: recordDropped}
+	s.swap(sampler)
+
+	return s
+}
+
+func (s *swappableSampler) swap(sampler sdktrace.Sampler) {
+	s.sampler.Store(&sampler)
+}
+
+// ShouldSample implements sdktrace.Sampler.
+func (s *swappableSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := (*s.sampler.Load()).ShouldSample(p)
+	if s.recordDropped && result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+
+	return result
+}
+
+// Description implements sdktrace.Sampler.
+func (s *swappableSampler) Description() string {
+	return (*s.sampler.Load()).Description()
+}
+
+// knownUnsampled tells whether the installed sampler drops the child span of an unsampled parent in ctx.
+// The decision of a sampled parent or a new root isn't known before the span starts, nor with a trace
+// provider installed without Enable.
+func knownUnsampled(ctx context.Context, name string, kind oteltracer.SpanKind) bool {
+	parent := oteltracer.SpanContextFromContext(ctx)
+	if !parent.IsValid() || parent.IsSampled() {
+		return false
+	}
+
+	pipeline := activePipeline.Load()
+	if pipeline == nil {
+		return false
+	}
+
+	result := pipeline.sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       parent.TraceID(),
+		Name:          name,
+		Kind:          kind,
+	})
+
+	return result.Decision == sdktrace.Drop
+}
@@ -0,0 +1,186 @@
+package coretracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+func newSampledTracer(t *testing.T, sampling SamplingConfig) (Tracer, *tracetest.InMemoryExporter) {
+	cfg := &Config{
+		EnvName:  "test",
+		Sampling: sampling,
+	}
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(cfg.Sampler()),
+		sdktrace.WithSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter)),
+	)
+	otel.SetTracerProvider(tp)
+
+	tracer := newOtelTracer(cfg)
+	t.Cleanup(tracer.Close)
+
+	return tracer, exporter
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+
+	return names
+}
+
+func TestSampler_Default(t *testing.T) {
+	var cfg *Config
+
+	require.Equal(t, sdktrace.AlwaysSample().Description(), cfg.Sampler().Description())
+	require.Equal(t, sdktrace.AlwaysSample().Description(), (&Config{}).Sampler().Description())
+}
+
+func TestSampler_InvalidConfigFallsBack(t *testing.T) {
+	for _, sampling := range []SamplingConfig{
+		{Sampler: "sometimes"},
+		{Sampler: SamplerTraceIDRatio, Ratio: 1.5},
+		{SpanNameRatios: map[string]float64{"EndBlocker": -1}},
+	} {
+		_, err := sampling.build()
+		require.Error(t, err)
+
+		cfg := &Config{Sampling: sampling}
+		require.Equal(t, sdktrace.AlwaysSample().Description(), cfg.Sampler().Description())
+	}
+}
+
+func TestSampler_AlwaysOffSkipsSpans(t *testing.T) {
+	tracer, exporter := newSampledTracer(t, SamplingConfig{Sampler: SamplerAlwaysOff})
+
+	ctx := context.Background()
+	endFn := tracer.TraceWithName(&ctx, "parent", NewTag("k", "v"))
+	tracer.TraceWithName(&ctx, "child")()
+	tracer.TraceError(ctx, context.Canceled)
+	endFn()
+
+	require.Empty(t, exporter.GetSpans())
+}
+
+func TestSampler_SpanNameRatios(t *testing.T) {
+	tracer, exporter := newSampledTracer(t, SamplingConfig{
+		Sampler: SamplerParentBasedAlwaysOff,
+		SpanNameRatios: map[string]float64{
+			"EndBlocker": 1,
+			"GetBalance": 0,
+		},
+	})
+
+	for _, name := range []string{"EndBlocker", "GetBalance", "Other"} {
+		ctx := context.Background()
+		endFn := tracer.TraceWithName(&ctx, name)
+		tracer.TraceWithName(&ctx, name+"/child")()
+		endFn()
+	}
+
+	// parent-based sampling keeps children of sampled roots only
+	require.ElementsMatch(t, []string{"EndBlocker", "EndBlocker/child"}, spanNames(exporter.GetSpans()))
+}
+
+func TestSampler_SampledSpanKeepsTags(t *testing.T) {
+	tracer, exporter := newSampledTracer(t, SamplingConfig{Sampler: SamplerTraceIDRatio, Ratio: 1})
+
+	ctx := context.Background()
+	tracer.TraceWithName(&ctx, "tagged", NewTag("block_height", 42))()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0].Attributes, 1)
+	require.Equal(t, "block_height", string(spans[0].Attributes[0].Key))
+	require.Equal(t, int64(42), spans[0].Attributes[0].Value.AsInt64())
+}
+
+// attributesSampler samples everything, keeping the attributes seen at start.
+type attributesSampler struct {
+	attributes []otelattribute.KeyValue
+}
+
+func (s *attributesSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	s.attributes = append(s.attributes, p.Attributes...)
+
+	return sdktrace.AlwaysSample().ShouldSample(p)
+}
+
+func (s *attributesSampler) Description() string {
+	return "AttributesSampler"
+}
+
+func TestSampler_SeesTags(t *testing.T) {
+	sampler := &attributesSampler{}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler)))
+
+	tracer := newOtelTracer(&Config{EnvName: "test"})
+	t.Cleanup(tracer.Close)
+
+	ctx := context.Background()
+	tracer.TraceWithName(&ctx, "tagged", NewTag("block_height", 42))()
+
+	require.Equal(t, []otelattribute.KeyValue{otelattribute.Int("block_height", 42)}, sampler.attributes)
+}
+
+func TestKnownUnsampled(t *testing.T) {
+	prev := activePipeline.Load()
+	t.Cleanup(func() { activePipeline.Store(prev) })
+
+	traceID := oteltracer.TraceID{1}
+	unsampled := oteltracer.ContextWithRemoteSpanContext(context.Background(), oteltracer.NewSpanContext(oteltracer.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  oteltracer.SpanID{1},
+	}))
+	sampled := oteltracer.ContextWithRemoteSpanContext(context.Background(), oteltracer.NewSpanContext(oteltracer.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     oteltracer.SpanID{1},
+		TraceFlags: oteltracer.FlagsSampled,
+	}))
+
+	activePipeline.Store(nil)
+	require.False(t, knownUnsampled(unsampled, "child", oteltracer.SpanKindInternal), "Expected no decision without a pipeline")
+
+	activePipeline.Store(&tracePipeline{sampler: newSwappableSampler(sdktrace.ParentBased(sdktrace.AlwaysSample()), false)})
+	require.True(t, knownUnsampled(unsampled, "child", oteltracer.SpanKindInternal))
+	require.False(t, knownUnsampled(sampled, "child", oteltracer.SpanKindInternal))
+	require.False(t, knownUnsampled(context.Background(), "root", oteltracer.SpanKindInternal))
+
+	activePipeline.Store(&tracePipeline{sampler: newSwappableSampler(sdktrace.AlwaysSample(), false)})
+	require.False(t, knownUnsampled(unsampled, "child", oteltracer.SpanKindInternal))
+
+	// span metrics record the dropped spans
+	activePipeline.Store(&tracePipeline{sampler: newSwappableSampler(sdktrace.ParentBased(sdktrace.AlwaysSample()), true)})
+	require.False(t, knownUnsampled(unsampled, "child", oteltracer.SpanKindInternal))
+}
+
+func TestSampler_SpanNameRatiosKeepChildren(t *testing.T) {
+	tracer, exporter := newSampledTracer(t, SamplingConfig{
+		Sampler: SamplerAlwaysOn,
+		SpanNameRatios: map[string]float64{
+			"GetBalance": 0,
+		},
+	})
+
+	ctx := context.Background()
+	endFn := tracer.TraceWithName(&ctx, "EndBlocker")
+	tracer.TraceWithName(&ctx, "GetBalance")()
+	endFn()
+
+	ctx = context.Background()
+	tracer.TraceWithName(&ctx, "GetBalance")()
+
+	// the override drops the GetBalance root, not the child of a sampled EndBlocker
+	require.ElementsMatch(t, []string{"EndBlocker", "GetBalance"}, spanNames(exporter.GetSpans()))
+}
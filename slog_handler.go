@@ -0,0 +1,223 @@
+package coretracer
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+// Log attribute keys linking log records to spans.
+const (
+	LogTraceIDKey    = "trace_id"
+	LogSpanIDKey     = "span_id"
+	LogTraceFlagsKey = "trace_flags"
+)
+
+var _ slog.Handler = (*traceContextHandler)(nil)
+
+// NewTraceContextHandler wraps the slog handler, adding trace_id, span_id and trace_flags attributes
+// of the span in the record context, so log lines can be linked to traces:
+//
+//	logger := slog.New(coretracer.NewTraceContextHandler(slog.NewJSONHandler(os.Stdout, nil)))
+//
+//	defer coretracer.Trace(&ctx)()
+//	logger.InfoContext(ctx, "order placed")
+//
+// Records logged without a context or outside of a span are passed as is.
+// After WithGroup, the attributes are nested in the group like any other record attribute.
+func NewTraceContextHandler(next slog.Handler) slog.Handler {
+	return &traceContextHandler{next: next}
+}
+
+type traceContextHandler struct {
+	next slog.Handler
+}
+
+// Enabled implements slog.Handler.
+func (h *traceContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *traceContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		return h.next.Handle(ctx, r)
+	}
+
+	spanCtx := oteltracer.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return h.next.Handle(ctx, r)
+	}
+
+	// the record might share its attributes with other handlers
+	r = r.Clone()
+	r.AddAttrs(
+		slog.String(LogTraceIDKey, spanCtx.TraceID().String()),
+		slog.String(LogSpanIDKey, spanCtx.SpanID().String()),
+		slog.String(LogTraceFlagsKey, spanCtx.TraceFlags().String()),
+	)
+
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *traceContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &traceContextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *traceContextHandler) WithGroup(name string) slog.Handler {
+	return &traceContextHandler{next: h.next.WithGroup(name)}
+}
+
+// SpanEventHandlerOptions configures NewSpanEventHandler.
+type SpanEventHandlerOptions struct {
+	// Level is the minimum level of records mirrored into spans. Defaults to slog.LevelWarn.
+	Level slog.Leveler
+	// SetErrorStatus marks the span as failed on records at slog.LevelError and above.
+	SetErrorStatus bool
+}
+
+var _ slog.Handler = (*spanEventHandler)(nil)
+
+// NewSpanEventHandler wraps the slog handler, mirroring Warn and Error records into events of the span
+// in the record context, with the record attributes converted like tags. Error records with an error
+// attribute are recorded as exceptions, once per span: TraceError skips errors already logged this way
+// and the other way around. Records are passed to the wrapped handler as usual, so it can be combined
+// with NewTraceContextHandler:
+//
+//	logger := slog.New(coretracer.NewTraceContextHandler(coretracer.NewSpanEventHandler(
+//		slog.NewJSONHandler(os.Stdout, nil),
+//		coretracer.SpanEventHandlerOptions{SetErrorStatus: true},
+//	)))
+//
+//	logger.ErrorContext(ctx, "failed to place order", "order_id", 42, "error", err)
+func NewSpanEventHandler(next slog.Handler, opts SpanEventHandlerOptions) slog.Handler {
+	if opts.Level == nil {
+		opts.Level = slog.LevelWarn
+	}
+
+	return &spanEventHandler{
+		next: next,
+		opts: opts,
+	}
+}
+
+type spanEventHandler struct {
+	next slog.Handler
+	opts SpanEventHandlerOptions
+
+	// attributes added with WithAttrs, converted
+	attributes []otelattribute.KeyValue
+	// groupPrefix is the key prefix of the groups opened with WithGroup, e.g. "request."
+	groupPrefix string
+}
+
+// Enabled implements slog.Handler.
+func (h *spanEventHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level() || h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *spanEventHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil && r.Level >= h.opts.Level.Level() {
+		h.mirror(oteltracer.SpanFromContext(ctx), r)
+	}
+
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *spanEventHandler) mirror(span oteltracer.Span, r slog.Record) {
+	if !span.IsRecording() {
+		return
+	}
+
+	attributes := make([]otelattribute.KeyValue, 0, len(h.attributes)+r.NumAttrs()+1)
+	attributes = append(attributes, otelattribute.String("log.severity", r.Level.String()))
+	attributes = append(attributes, h.attributes...)
+
+	var err error
+
+	r.Attrs(func(attr slog.Attr) bool {
+		if recordErr, ok := attr.Value.Resolve().Any().(error); ok && err == nil {
+			err = recordErr
+		}
+
+		attributes = appendSlogAttr(attributes, h.groupPrefix, attr)
+		return true
+	})
+
+	isError := r.Level >= slog.LevelError
+
+	switch {
+	case isError && err != nil:
+		if !errorRecorded(span, err) {
+			attributes = append(attributes, otelattribute.String("log.message", r.Message))
+			span.RecordError(err, oteltracer.WithAttributes(attributes...), oteltracer.WithTimestamp(r.Time))
+		}
+	default:
+		span.AddEvent(r.Message, oteltracer.WithAttributes(attributes...), oteltracer.WithTimestamp(r.Time))
+	}
+
+	if isError && h.opts.SetErrorStatus {
+		span.SetStatus(otelcodes.Error, r.Message)
+	}
+}
+
+// WithAttrs implements slog.Handler.
+func (h *spanEventHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.next = h.next.WithAttrs(attrs)
+	h2.attributes = slices.Clip(h.attributes)
+
+	for _, attr := range attrs {
+		h2.attributes = appendSlogAttr(h2.attributes, h.groupPrefix, attr)
+	}
+
+	return &h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *spanEventHandler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return h
+	}
+
+	h2 := *h
+	h2.next = h.next.WithGroup(name)
+	h2.groupPrefix = h.groupPrefix + name + "."
+
+	return &h2
+}
+
+// appendSlogAttr converts the attribute like tags are, flattening groups into dotted keys.
+func appendSlogAttr(attributes []otelattribute.KeyValue, prefix string, attr slog.Attr) []otelattribute.KeyValue {
+	value := attr.Value.Resolve()
+
+	if value.Kind() != slog.KindGroup {
+		if len(attr.Key) == 0 {
+			return attributes
+		}
+
+		return append(attributes, anyToOtalAttribute(prefix+attr.Key, value.Any()))
+	}
+
+	// inline groups have no key
+	if len(attr.Key) > 0 {
+		prefix += attr.Key + "."
+	}
+
+	for _, groupAttr := range value.Group() {
+		attributes = appendSlogAttr(attributes, prefix, groupAttr)
+	}
+
+	return attributes
+}
@@ -0,0 +1,131 @@
+package coretracer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		record := make(map[string]any)
+		require.NoError(t, json.Unmarshal(line, &record))
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestTraceContextHandler(t *testing.T) {
+	tracer, exporter := newSampledTracer(t, SamplingConfig{})
+
+	var buf bytes.Buffer
+	logger := slog.New(NewTraceContextHandler(slog.NewJSONHandler(&buf, nil))).With("service", "api")
+
+	ctx := context.Background()
+	logger.InfoContext(ctx, "outside of span")
+
+	endFn := tracer.TraceWithName(&ctx, "PlaceOrder")
+	logger.InfoContext(ctx, "order placed", "order_id", 42)
+	endFn()
+
+	logger.Info("without context")
+
+	spans := exporter.GetSpans()
+	require.NotEmpty(t, spans)
+
+	span := spans[0]
+	require.Equal(t, "PlaceOrder", span.Name)
+
+	records := decodeLogLines(t, &buf)
+	require.Len(t, records, 3)
+
+	require.NotContains(t, records[0], LogTraceIDKey)
+	require.NotContains(t, records[2], LogTraceIDKey)
+
+	require.Equal(t, "api", records[1]["service"])
+	require.EqualValues(t, 42, records[1]["order_id"])
+	require.Equal(t, span.SpanContext.TraceID().String(), records[1][LogTraceIDKey])
+	require.Equal(t, span.SpanContext.SpanID().String(), records[1][LogSpanIDKey])
+	require.Equal(t, "01", records[1][LogTraceFlagsKey])
+}
+
+func TestSpanEventHandler(t *testing.T) {
+	tracer, exporter := newSampledTracer(t, SamplingConfig{})
+
+	var buf bytes.Buffer
+	logger := slog.New(NewSpanEventHandler(
+		slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError}),
+		SpanEventHandlerOptions{SetErrorStatus: true},
+	)).With("service", "api").WithGroup("order")
+
+	ctx := context.Background()
+	endFn := tracer.TraceWithName(&ctx, "PlaceOrder")
+
+	logger.InfoContext(ctx, "placing order", "id", 42)
+	logger.WarnContext(ctx, "slow market", "id", 42, slog.Group("market", "ticker", "INJ/USDT"), "retries", []int{1, 2})
+	logger.ErrorContext(ctx, "failed to place order", "id", 42, "error", errors.New("insufficient funds"))
+	logger.ErrorContext(ctx, "failed to place order again", "id", 42, "error", errors.New("insufficient funds"))
+	tracer.TraceError(ctx, errors.New("insufficient funds"))
+	endFn()
+
+	span := exporter.GetSpans()[0]
+	require.Equal(t, "PlaceOrder", span.Name)
+	require.Equal(t, otelcodes.Error, span.Status.Code, "Expected the error status to survive the span end")
+
+	require.Len(t, span.Events, 2)
+
+	warning := span.Events[0]
+	require.Equal(t, "slow market", warning.Name)
+	require.ElementsMatch(t, []otelattribute.KeyValue{
+		otelattribute.String("log.severity", "WARN"),
+		otelattribute.String("service", "api"),
+		otelattribute.Int64("order.id", 42),
+		otelattribute.String("order.market.ticker", "INJ/USDT"),
+		otelattribute.IntSlice("order.retries", []int{1, 2}),
+	}, warning.Attributes)
+
+	exception := span.Events[1]
+	require.Equal(t, "exception", exception.Name)
+	require.Contains(t, exception.Attributes, otelattribute.String("exception.message", "insufficient funds"))
+	require.Contains(t, exception.Attributes, otelattribute.String("log.message", "failed to place order"))
+
+	records := decodeLogLines(t, &buf)
+	require.Len(t, records, 2, "Expected the wrapped handler to apply its own level")
+}
+
+func TestSpanEventHandler_TraceErrorAfterLog(t *testing.T) {
+	tracer, exporter := newSampledTracer(t, SamplingConfig{})
+
+	logger := slog.New(NewSpanEventHandler(slog.DiscardHandler, SpanEventHandlerOptions{}))
+
+	ctx := context.Background()
+	_ = tracer.TraceWithName(&ctx, "PlaceOrder")
+
+	err := errors.New("insufficient funds")
+	logger.ErrorContext(ctx, "failed to place order", "error", err)
+	tracer.TraceError(ctx, err, NewTags().With("order.id", 42))
+
+	span := exporter.GetSpans()[0]
+	require.Equal(t, otelcodes.Error, span.Status.Code)
+	require.Len(t, span.Events, 1, "Expected a single exception event")
+	require.Contains(t, span.Attributes, otelattribute.Int("order.id", 42), "Expected the TraceError tags on the span")
+
+	var stackTrace string
+	for _, attr := range span.Attributes {
+		if attr.Key == "exception.stacktrace" {
+			stackTrace = attr.Value.AsString()
+		}
+	}
+
+	require.Contains(t, stackTrace, "TestSpanEventHandler_TraceErrorAfterLog", "Expected the TraceError stack trace on the span")
+}
@@ -0,0 +1,40 @@
+package coretracer
+
+import (
+	"context"
+
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+// SpanKind tells the role of a span in a trace, e.g. the server side of a request.
+// Spans are internal unless started with a context from ContextWithSpanKind.
+type SpanKind = oteltracer.SpanKind
+
+// Span kinds used by instrumentations of RPCs and messaging.
+const (
+	SpanKindInternal = oteltracer.SpanKindInternal
+	SpanKindServer   = oteltracer.SpanKindServer
+	SpanKindClient   = oteltracer.SpanKindClient
+	SpanKindProducer = oteltracer.SpanKindProducer
+	SpanKindConsumer = oteltracer.SpanKindConsumer
+)
+
+type spanKindKey struct{}
+
+// ContextWithSpanKind returns a copy of ctx, so the next span started from it has the given kind,
+// e.g. the server span of an incoming request:
+//
+//	ctx = coretracer.ContextWithSpanKind(ctx, coretracer.SpanKindServer)
+//	defer coretracer.TraceWithName(&ctx, "GET /orders/{id}")()
+//
+// The kind applies to that span only, its children are internal again.
+func ContextWithSpanKind(ctx context.Context, kind SpanKind) context.Context {
+	return context.WithValue(ctx, spanKindKey{}, kind)
+}
+
+// spanKindFromContext returns the kind set by ContextWithSpanKind, if any.
+func spanKindFromContext(ctx context.Context) (SpanKind, bool) {
+	kind, ok := ctx.Value(spanKindKey{}).(SpanKind)
+
+	return kind, ok
+}
@@ -0,0 +1,148 @@
+package coretracer
+
+import (
+	"context"
+	"fmt"
+
+	otel "go.opentelemetry.io/otel"
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+// Span metric names, with the span name in the SpanNameKey attribute.
+const (
+	MetricSpanCalls    = "coretracer.span.calls"
+	MetricSpanErrors   = "coretracer.span.errors"
+	MetricSpanDuration = "coretracer.span.duration"
+
+	SpanNameKey = "span.name"
+)
+
+// defaultDurationBuckets are the duration histogram bounds in seconds, from 1ms to 5m.
+var defaultDurationBuckets = []float64{
+	0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300,
+}
+
+// SpanMetricsConfig configures RED metrics derived from spans: calls, errors and durations
+// per span name, recorded through the OpenTelemetry metrics API. When enabled, spans dropped
+// by head sampling are still recorded, but not exported, so metrics count every call.
+// Tail sampling doesn't affect metrics either.
+type SpanMetricsConfig struct {
+	// Enabled switches span metrics on.
+	Enabled bool
+	// TagKeys are the span tags added to the metric attributes, e.g. "chain_id". Every distinct
+	// value makes a new time series, so only tags with a few possible values should be listed.
+	TagKeys []string
+	// MeterProvider creates the instruments, defaults to the global otel.GetMeterProvider().
+	MeterProvider metric.MeterProvider
+	// DurationBuckets are the bounds of the duration histogram in seconds. Defaults to
+	// 1ms, 5ms, 10ms, 25ms, 50ms, 100ms, 250ms, 500ms, 1s, 2.5s, 5s, 10s, 30s, 1m and 5m.
+	DurationBuckets []float64
+}
+
+var _ sdktrace.SpanProcessor = (*spanMetricsProcessor)(nil)
+
+// spanMetricsProcessor records every ended span, sampled or not, into the RED metrics.
+type spanMetricsProcessor struct {
+	tagKeys map[otelattribute.Key]struct{}
+
+	calls    metric.Int64Counter
+	errors   metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+func newSpanMetricsProcessor(cfg SpanMetricsConfig) (*spanMetricsProcessor, error) {
+	meterProvider := cfg.MeterProvider
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+
+	buckets := cfg.DurationBuckets
+	if len(buckets) == 0 {
+		buckets = defaultDurationBuckets
+	}
+
+	meter := meterProvider.Meter("github.com/InjectiveLabs/coretracer")
+
+	p := &spanMetricsProcessor{
+		tagKeys: make(map[otelattribute.Key]struct{}, len(cfg.TagKeys)),
+	}
+
+	for _, key := range cfg.TagKeys {
+		p.tagKeys[otelattribute.Key(key)] = struct{}{}
+	}
+
+	var err error
+
+	p.calls, err = meter.Int64Counter(MetricSpanCalls,
+		metric.WithDescription("Number of ended spans."),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s counter: %w", MetricSpanCalls, err)
+	}
+
+	p.errors, err = meter.Int64Counter(MetricSpanErrors,
+		metric.WithDescription("Number of ended spans with an error."),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s counter: %w", MetricSpanErrors, err)
+	}
+
+	p.duration, err = meter.Float64Histogram(MetricSpanDuration,
+		metric.WithDescription("Duration of ended spans."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(buckets...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s histogram: %w", MetricSpanDuration, err)
+	}
+
+	return p, nil
+}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (p *spanMetricsProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (p *spanMetricsProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	attributes := make([]otelattribute.KeyValue, 0, len(p.tagKeys)+1)
+	attributes = append(attributes, otelattribute.String(SpanNameKey, s.Name()))
+
+	if len(p.tagKeys) > 0 {
+		for _, attr := range s.Attributes() {
+			if _, ok := p.tagKeys[attr.Key]; ok {
+				attributes = append(attributes, attr)
+			}
+		}
+	}
+
+	opt := metric.WithAttributeSet(otelattribute.NewSet(attributes...))
+
+	// the span context lets the metrics SDK attach trace exemplars of sampled spans
+	ctx := oteltracer.ContextWithSpanContext(context.Background(), s.SpanContext())
+
+	p.calls.Add(ctx, 1, opt)
+
+	if spanFailed(s) {
+		p.errors.Add(ctx, 1, opt)
+	}
+
+	p.duration.Record(ctx, s.EndTime().Sub(s.StartTime()).Seconds(), opt)
+}
+
+// ForceFlush implements sdktrace.SpanProcessor.
+func (p *spanMetricsProcessor) ForceFlush(ctx context.Context) error { return nil }
+
+// Shutdown implements sdktrace.SpanProcessor.
+func (p *spanMetricsProcessor) Shutdown(ctx context.Context) error { return nil }
+
+// spanFailed tells whether the span ended with the error status, e.g. set by TraceError.
+// Exceptions alone don't count: an error log mirrored into a span ending Ok is not a failure.
+func spanFailed(s sdktrace.ReadOnlySpan) bool {
+	return s.Status().Code == otelcodes.Error
+}
@@ -0,0 +1,113 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func collectMetric(t *testing.T, reader sdkmetric.Reader, name string) metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+
+	require.Failf(t, "metric not found", "Expected metric %s", name)
+
+	return nil
+}
+
+func sumByAttributes(sum metricdata.Sum[int64]) map[otelattribute.Distinct]int64 {
+	values := make(map[otelattribute.Distinct]int64, len(sum.DataPoints))
+	for _, point := range sum.DataPoints {
+		values[point.Attributes.Equivalent()] = point.Value
+	}
+
+	return values
+}
+
+func balanceAttributes(chainID string) otelattribute.Distinct {
+	set := otelattribute.NewSet(
+		otelattribute.String(SpanNameKey, "GetBalance"),
+		otelattribute.String("chain_id", chainID),
+	)
+
+	return set.Equivalent()
+}
+
+func TestSpanMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	exporter := tracetest.NewInMemoryExporter()
+
+	shutdownFn, err := InitTraceProvider(&Config{
+		Sampling: SamplingConfig{Sampler: SamplerAlwaysOff},
+		SpanMetrics: SpanMetricsConfig{
+			Enabled:       true,
+			TagKeys:       []string{"chain_id"},
+			MeterProvider: meterProvider,
+		},
+	}, exporter)
+	require.NoError(t, err)
+	defer func() { _ = shutdownFn(context.Background()) }()
+
+	tracer := newOtelTracer(&Config{})
+	defer tracer.Close()
+
+	for _, chainID := range []string{"injective-1", "injective-1", "injective-888"} {
+		ctx := context.Background()
+		tracer.TraceWithName(&ctx, "GetBalance", NewTags().With("chain_id", chainID).With("address", "inj1..."))()
+	}
+
+	ctx := context.Background()
+	endFn := tracer.TraceWithName(&ctx, "GetBalance", NewTag("chain_id", "injective-1"))
+	tracer.TraceError(ctx, errors.New("account not found"))
+	endFn()
+
+	// an error log mirrored into a span ending Ok is not a failure
+	logger := slog.New(NewSpanEventHandler(slog.DiscardHandler, SpanEventHandlerOptions{}))
+
+	ctx = context.Background()
+	endFn = tracer.TraceWithName(&ctx, "GetBalance", NewTag("chain_id", "injective-888"))
+	logger.ErrorContext(ctx, "retrying", "error", errors.New("timeout"))
+	endFn()
+
+	flushProvider(t)
+	require.Empty(t, exporter.GetSpans(), "Expected sampled out spans not to be exported")
+
+	mainnet := balanceAttributes("injective-1")
+	testnet := balanceAttributes("injective-888")
+
+	calls := sumByAttributes(collectMetric(t, reader, MetricSpanCalls).(metricdata.Sum[int64]))
+	require.EqualValues(t, 3, calls[mainnet])
+	require.EqualValues(t, 2, calls[testnet])
+
+	errorCounts := sumByAttributes(collectMetric(t, reader, MetricSpanErrors).(metricdata.Sum[int64]))
+	require.Equal(t, map[otelattribute.Distinct]int64{mainnet: 1}, errorCounts)
+
+	histogram := collectMetric(t, reader, MetricSpanDuration).(metricdata.Histogram[float64])
+
+	var durations uint64
+	for _, point := range histogram.DataPoints {
+		if point.Attributes.Equivalent() == mainnet {
+			durations = point.Count
+			require.Equal(t, defaultDurationBuckets, point.Bounds)
+		}
+	}
+
+	require.EqualValues(t, 3, durations)
+}
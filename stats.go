@@ -0,0 +1,154 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ExporterStats is the self-telemetry of the span pipeline, cumulative since process start.
+// With several exporters, export counters add up across them, e.g. a span delivered
+// to two destinations counts twice in SpansExported.
+type ExporterStats struct {
+	// Covered tells whether the counters track the active trace provider. It's false when tracing
+	// is disabled, or the provider was installed by a custom init function passed to Enable:
+	// its spans are not counted then, counters only hold what earlier providers collected.
+	Covered bool
+	// SpansStarted and SpansEnded count sampled spans.
+	SpansStarted uint64
+	SpansEnded   uint64
+	// SpansExported counts spans accepted by the exporters.
+	SpansExported uint64
+	// SpansDropped counts spans that never reached the exporters, because the queue was full.
+	SpansDropped uint64
+	// SpansFailed counts spans lost in failed exports.
+	SpansFailed uint64
+	// SpansSpooled counts spans kept on disk by the exporters to be replayed later, see SpoolConfig.
+	// They are not counted in SpansExported, as they haven't reached the collector yet.
+	SpansSpooled uint64
+	// FailedExports counts failed export calls, i.e. batches.
+	FailedExports uint64
+	// TracesKept and TracesDiscarded count tail sampling decisions, see TailSamplingConfig.
+	TracesKept      uint64
+	TracesDiscarded uint64
+	// TracesEvicted counts traces decided before their local root span ended, because the buffer was full.
+	TracesEvicted uint64
+	// SpansDiscarded counts spans of the traces discarded by tail sampling.
+	SpansDiscarded uint64
+	// LastExportError is the error of the latest failed export.
+	LastExportError error
+	// LastExportErrorTime is the time of the latest failed export.
+	LastExportErrorTime time.Time
+	// LastExportTime is the time of the latest successful export.
+	LastExportTime time.Time
+}
+
+// Stats returns the exporter statistics. Counters are collected by the trace provider set up
+// by InitTraceProvider, InitMultiExporter or InitExporters, which all the exporter packages use.
+// Spans of a trace provider installed otherwise, e.g. by a custom init function passed
+// to Enable, are not counted, check Covered before reading zeros as no loss.
+func Stats() ExporterStats {
+	stats := globalStats.snapshot()
+	stats.Covered = activePipeline.Load() != nil
+
+	return stats
+}
+
+// ErrSpooled is returned by exporters that kept the spans on disk instead of sending them,
+// so they are counted in SpansSpooled rather than as exported or failed.
+var ErrSpooled = errors.New("coretracer: spans spooled for a later export")
+
+var globalStats = new(exporterStats)
+
+type exporterStats struct {
+	spansStarted  atomic.Uint64
+	spansEnded    atomic.Uint64
+	spansExported atomic.Uint64
+	spansDropped  atomic.Uint64
+	spansFailed   atomic.Uint64
+	spansSpooled  atomic.Uint64
+	failedExports atomic.Uint64
+
+	tracesKept      atomic.Uint64
+	tracesDiscarded atomic.Uint64
+	tracesEvicted   atomic.Uint64
+	spansDiscarded  atomic.Uint64
+
+	mux                 sync.Mutex
+	lastExportError     error
+	lastExportErrorTime time.Time
+	lastExportTime      time.Time
+}
+
+func (s *exporterStats) exportSucceeded(spans int) {
+	s.spansExported.Add(uint64(spans))
+
+	s.mux.Lock()
+	s.lastExportTime = time.Now()
+	s.mux.Unlock()
+}
+
+func (s *exporterStats) exportFailed(err error, spans int) {
+	s.spansFailed.Add(uint64(spans))
+	s.failedExports.Add(1)
+
+	s.mux.Lock()
+	s.lastExportError = err
+	s.lastExportErrorTime = time.Now()
+	s.mux.Unlock()
+}
+
+func (s *exporterStats) exportSpooled(spans int) {
+	s.spansSpooled.Add(uint64(spans))
+}
+
+func (s *exporterStats) snapshot() ExporterStats {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return ExporterStats{
+		SpansStarted:        s.spansStarted.Load(),
+		SpansEnded:          s.spansEnded.Load(),
+		SpansExported:       s.spansExported.Load(),
+		SpansDropped:        s.spansDropped.Load(),
+		SpansFailed:         s.spansFailed.Load(),
+		SpansSpooled:        s.spansSpooled.Load(),
+		FailedExports:       s.failedExports.Load(),
+		TracesKept:          s.tracesKept.Load(),
+		TracesDiscarded:     s.tracesDiscarded.Load(),
+		TracesEvicted:       s.tracesEvicted.Load(),
+		SpansDiscarded:      s.spansDiscarded.Load(),
+		LastExportError:     s.lastExportError,
+		LastExportErrorTime: s.lastExportErrorTime,
+		LastExportTime:      s.lastExportTime,
+	}
+}
+
+var _ sdktrace.SpanProcessor = (*statsSpanProcessor)(nil)
+
+// statsSpanProcessor counts started and ended spans, once per provider.
+type statsSpanProcessor struct{}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (statsSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if s.SpanContext().IsSampled() {
+		globalStats.spansStarted.Add(1)
+	}
+}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (statsSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		globalStats.spansEnded.Add(1)
+	}
+}
+
+// ForceFlush implements sdktrace.SpanProcessor.
+func (statsSpanProcessor) ForceFlush(ctx context.Context) error { return nil }
+
+// Shutdown implements sdktrace.SpanProcessor.
+func (statsSpanProcessor) Shutdown(ctx context.Context) error { return nil }
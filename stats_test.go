@@ -0,0 +1,163 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// toggleExporter fails exports while broken is set.
+type toggleExporter struct {
+	*tracetest.InMemoryExporter
+	broken atomic.Bool
+}
+
+func (e *toggleExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if e.broken.Load() {
+		return errors.New("collector is down")
+	}
+
+	return e.InMemoryExporter.ExportSpans(ctx, spans)
+}
+
+func flushProvider(t *testing.T) {
+	require.NoError(t, otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background()))
+}
+
+func TestStats(t *testing.T) {
+	exporter := &toggleExporter{InMemoryExporter: tracetest.NewInMemoryExporter()}
+
+	shutdownFn, err := InitTraceProvider(&Config{
+		Sampling: SamplingConfig{
+			SpanNameRatios: map[string]float64{"unsampled": 0},
+			Sampler:        SamplerTraceIDRatio,
+			Ratio:          1,
+		},
+	}, exporter)
+	require.NoError(t, err)
+
+	tracer := newOtelTracer(&Config{})
+	defer tracer.Close()
+
+	before := Stats()
+
+	ctx := context.Background()
+	tracer.TraceWithName(&ctx, "exported")()
+
+	unsampledCtx := context.Background()
+	tracer.TraceWithName(&unsampledCtx, "unsampled")()
+
+	unfinishedCtx := context.Background()
+	endFn := tracer.TraceWithName(&unfinishedCtx, "unfinished")
+
+	flushProvider(t)
+
+	stats := Stats()
+	require.EqualValues(t, 2, stats.SpansStarted-before.SpansStarted)
+	require.EqualValues(t, 1, stats.SpansEnded-before.SpansEnded)
+	require.EqualValues(t, 1, stats.SpansExported-before.SpansExported)
+	require.WithinDuration(t, time.Now(), stats.LastExportTime, time.Second)
+
+	exporter.broken.Store(true)
+	endFn()
+	flushProvider(t)
+
+	stats = Stats()
+	require.EqualValues(t, 1, stats.SpansFailed-before.SpansFailed)
+	require.EqualValues(t, 1, stats.FailedExports-before.FailedExports)
+	require.EqualError(t, stats.LastExportError, "collector is down")
+	require.WithinDuration(t, time.Now(), stats.LastExportErrorTime, time.Second)
+
+	require.NoError(t, shutdownFn(context.Background()))
+}
+
+// spoolingExporter keeps every batch for later, like an OTLP exporter with the collector down.
+type spoolingExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (e spoolingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return fmt.Errorf("%w: collector is down", ErrSpooled)
+}
+
+func TestStats_Spooled(t *testing.T) {
+	shutdownFn, err := InitTraceProvider(&Config{}, spoolingExporter{tracetest.NewInMemoryExporter()})
+	require.NoError(t, err)
+	defer func() { _ = shutdownFn(context.Background()) }()
+
+	tracer := newOtelTracer(&Config{})
+	defer tracer.Close()
+
+	before := Stats()
+
+	ctx := context.Background()
+	tracer.TraceWithName(&ctx, "spooled")()
+	flushProvider(t)
+
+	stats := Stats()
+	require.EqualValues(t, 1, stats.SpansSpooled-before.SpansSpooled)
+	require.EqualValues(t, 0, stats.SpansExported-before.SpansExported, "Expected spooled spans not to count as exported")
+	require.EqualValues(t, 0, stats.SpansFailed-before.SpansFailed)
+}
+
+func TestStats_DroppedWhenQueueIsFull(t *testing.T) {
+	exporter := &blockingExporter{releaseC: make(chan struct{})}
+
+	processor := newBatchSpanProcessor(exporter, batchOptions{
+		queueSize:     1,
+		batchSize:     1,
+		exportTimeout: time.Minute,
+		scheduleDelay: time.Minute,
+	})
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+	before := Stats()
+
+	endSpan := func() {
+		_, span := provider.Tracer("test").Start(context.Background(), "span")
+		span.End()
+	}
+
+	// the first span gets stuck in the export
+	endSpan()
+	require.Eventually(t, func() bool {
+		return len(processor.queue) == 0
+	}, time.Second, time.Millisecond)
+
+	// one more is queued, the rest are dropped
+	for i := 0; i < 9; i++ {
+		endSpan()
+	}
+
+	require.EqualValues(t, 8, Stats().SpansDropped-before.SpansDropped)
+
+	close(exporter.releaseC)
+	require.NoError(t, provider.Shutdown(context.Background()))
+}
+
+func TestStats_Covered(t *testing.T) {
+	shutdownFn, err := InitTraceProvider(&Config{}, tracetest.NewInMemoryExporter())
+	require.NoError(t, err)
+	require.True(t, Stats().Covered)
+
+	require.NoError(t, shutdownFn(context.Background()))
+	require.False(t, Stats().Covered)
+
+	Enable(&Config{}, func(cfg *Config) ExporterShutdownFn {
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter()))
+		otel.SetTracerProvider(tp)
+
+		return tp.Shutdown
+	})
+	defer Close()
+
+	require.False(t, Stats().Covered, "Expected a custom trace provider not to be covered")
+}
@@ -0,0 +1,342 @@
+package coretracer
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+// Tail sampling defaults.
+const (
+	defaultTailDecisionTimeout = 30 * time.Second
+	defaultTailMaxTraces       = 10000
+	defaultTailMaxSpans        = 100000
+)
+
+// TailSamplingConfig configures tail sampling, i.e. the decision made once the trace is complete.
+// Spans are buffered per trace until the local root span ends, then the whole trace is kept
+// if any of its spans recorded an error with TraceError, was flagged stuck by the watchdog,
+// or lasted longer than LatencyThreshold. Other traces are kept with the Ratio probability.
+// Tail sampling applies to the spans kept by head sampling, see SamplingConfig.
+// Decisions and evictions are counted in Stats.
+type TailSamplingConfig struct {
+	// Enabled switches tail sampling on.
+	Enabled bool
+	// LatencyThreshold keeps the traces with a span lasting at least that long, zero disables the check.
+	LatencyThreshold time.Duration
+	// Ratio is the fraction of the other traces to keep, in [0, 1]. The draw is independent of
+	// the head sampling one, so e.g. 0.5 after a 0.5 head ratio keeps a quarter of the traces.
+	Ratio float64
+	// DecisionTimeout limits the wait for the local root span, then the trace is decided
+	// with the spans buffered so far. Spans ending after the decision follow it. Defaults to 30s.
+	DecisionTimeout time.Duration
+	// MaxTraces limits the number of buffered traces. Defaults to 10000.
+	MaxTraces int
+	// MaxSpans limits the number of buffered spans across all traces. Defaults to 100000.
+	// When either limit is exceeded the oldest trace is evicted, i.e. decided early.
+	MaxSpans int
+}
+
+type tailSamplingOptions struct {
+	latencyThreshold time.Duration
+	ratio            float64
+	decisionTimeout  time.Duration
+	maxTraces        int
+	maxSpans         int
+}
+
+// tailSamplingOptions applies the defaults to the tail sampling config.
+func (c *Config) tailSamplingOptions() tailSamplingOptions {
+	s := c.TailSampling
+
+	return tailSamplingOptions{
+		latencyThreshold: max(s.LatencyThreshold, 0),
+		ratio:            min(max(s.Ratio, 0), 1),
+		decisionTimeout:  cmp.Or(max(s.DecisionTimeout, 0), defaultTailDecisionTimeout),
+		maxTraces:        cmp.Or(max(s.MaxTraces, 0), defaultTailMaxTraces),
+		maxSpans:         cmp.Or(max(s.MaxSpans, 0), defaultTailMaxSpans),
+	}
+}
+
+var _ sdktrace.SpanProcessor = (*tailSamplingProcessor)(nil)
+
+// tailSamplingProcessor buffers ended spans per trace and passes the kept traces to the next processors.
+type tailSamplingProcessor struct {
+	opts tailSamplingOptions
+	// seed salts the trace ID hash of the ratio draw, head samplers use the trace ID bits as is.
+	seed maphash.Seed
+	next []sdktrace.SpanProcessor
+
+	mux     sync.Mutex
+	pending map[oteltracer.TraceID]*tailTrace
+	order   *list.List // of *tailTrace, by first ended span
+	spans   int
+	// kept remembers the traces kept for an error or latency, so late spans are kept too.
+	// Ratio decisions don't need it, they are the same for every span of the trace.
+	kept map[oteltracer.TraceID]time.Time
+	// discarded remembers the discarded traces, so late spans are discarded even if notable.
+	discarded map[oteltracer.TraceID]time.Time
+	stopped   bool
+
+	stopC    chan struct{}
+	doneC    chan struct{}
+	stopOnce sync.Once
+}
+
+type tailTrace struct {
+	id        oteltracer.TraceID
+	spans     []sdktrace.ReadOnlySpan
+	firstSeen time.Time
+	// notable is set when a span has an error, is stuck or slow.
+	notable bool
+	elem    *list.Element
+}
+
+func newTailSamplingProcessor(opts tailSamplingOptions, next ...sdktrace.SpanProcessor) *tailSamplingProcessor {
+	p := &tailSamplingProcessor{
+		opts:      opts,
+		seed:      maphash.MakeSeed(),
+		next:      next,
+		pending:   make(map[oteltracer.TraceID]*tailTrace),
+		order:     list.New(),
+		kept:      make(map[oteltracer.TraceID]time.Time),
+		discarded: make(map[oteltracer.TraceID]time.Time),
+		stopC:     make(chan struct{}),
+		doneC:     make(chan struct{}),
+	}
+
+	go p.loop()
+
+	return p
+}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (p *tailSamplingProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, next := range p.next {
+		next.OnStart(parent, s)
+	}
+}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (p *tailSamplingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+
+	traceID := s.SpanContext().TraceID()
+	localRoot := !s.Parent().IsValid() || s.Parent().IsRemote()
+
+	p.mux.Lock()
+
+	if _, ok := p.kept[traceID]; ok || p.stopped {
+		p.mux.Unlock()
+		p.forward(s)
+
+		return
+	}
+
+	if _, ok := p.discarded[traceID]; ok {
+		p.mux.Unlock()
+		globalStats.spansDiscarded.Add(1)
+
+		return
+	}
+
+	trace, ok := p.pending[traceID]
+	if !ok {
+		trace = &tailTrace{
+			id:        traceID,
+			firstSeen: time.Now(),
+		}
+		trace.elem = p.order.PushBack(trace)
+		p.pending[traceID] = trace
+	}
+
+	trace.spans = append(trace.spans, s)
+	trace.notable = trace.notable || p.isNotable(s)
+	p.spans++
+
+	var decided []*tailTrace
+
+	if localRoot {
+		p.remove(trace)
+		decided = append(decided, trace)
+	}
+
+	for p.order.Len() > 0 && (len(p.pending) > p.opts.maxTraces || p.spans > p.opts.maxSpans) {
+		oldest := p.order.Front().Value.(*tailTrace)
+		p.remove(oldest)
+		decided = append(decided, oldest)
+
+		globalStats.tracesEvicted.Add(1)
+	}
+
+	p.mux.Unlock()
+
+	p.decide(decided)
+}
+
+// isNotable tells whether the span alone makes the trace worth keeping.
+func (p *tailSamplingProcessor) isNotable(s sdktrace.ReadOnlySpan) bool {
+	if p.opts.latencyThreshold > 0 && s.EndTime().Sub(s.StartTime()) >= p.opts.latencyThreshold {
+		return true
+	}
+
+	return spanFailed(s) || spanStuck(s)
+}
+
+// spanStuck tells whether the stuck function watchdog flagged the span,
+// the function might have completed afterwards, ending the span Ok.
+func spanStuck(s sdktrace.ReadOnlySpan) bool {
+	for _, attr := range s.Attributes() {
+		if attr.Key == "exception.type" && attr.Value.AsString() == "stuck" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remove takes the trace out of the buffer, must be called with mux locked.
+func (p *tailSamplingProcessor) remove(trace *tailTrace) {
+	delete(p.pending, trace.id)
+	p.order.Remove(trace.elem)
+	p.spans -= len(trace.spans)
+}
+
+// decide keeps or discards the traces taken out of the buffer.
+func (p *tailSamplingProcessor) decide(traces []*tailTrace) {
+	for _, trace := range traces {
+		keep := trace.notable
+		if !keep {
+			keep = p.sampledByRatio(trace.id)
+		}
+
+		if !keep {
+			p.remember(p.discarded, trace.id)
+
+			globalStats.tracesDiscarded.Add(1)
+			globalStats.spansDiscarded.Add(uint64(len(trace.spans)))
+
+			continue
+		}
+
+		if trace.notable {
+			p.remember(p.kept, trace.id)
+		}
+
+		globalStats.tracesKept.Add(1)
+		p.forward(trace.spans...)
+	}
+}
+
+// remember records the decision of the trace for its late spans, up to MaxTraces decisions.
+func (p *tailSamplingProcessor) remember(decisions map[oteltracer.TraceID]time.Time, traceID oteltracer.TraceID) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if len(decisions) < p.opts.maxTraces {
+		decisions[traceID] = time.Now()
+	}
+}
+
+// sampledByRatio draws whether the trace is kept by the ratio, the same way for every span of the trace.
+func (p *tailSamplingProcessor) sampledByRatio(traceID oteltracer.TraceID) bool {
+	// the top 53 bits make a uniform float in [0, 1)
+	draw := float64(maphash.Bytes(p.seed, traceID[:])>>11) / (1 << 53)
+
+	return draw < p.opts.ratio
+}
+
+func (p *tailSamplingProcessor) forward(spans ...sdktrace.ReadOnlySpan) {
+	for _, s := range spans {
+		for _, next := range p.next {
+			next.OnEnd(s)
+		}
+	}
+}
+
+// ForceFlush implements sdktrace.SpanProcessor. Traces still waiting for their
+// local root are not decided, so they are never broken by a flush.
+func (p *tailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	for _, next := range p.next {
+		if err := next.ForceFlush(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanProcessor. Decides all the buffered traces,
+// the next processors are shut down by their owner.
+func (p *tailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		close(p.stopC)
+		<-p.doneC
+
+		p.mux.Lock()
+		p.stopped = true
+
+		decided := make([]*tailTrace, 0, p.order.Len())
+		for p.order.Len() > 0 {
+			trace := p.order.Front().Value.(*tailTrace)
+			p.remove(trace)
+			decided = append(decided, trace)
+		}
+		p.mux.Unlock()
+
+		p.decide(decided)
+	})
+
+	return nil
+}
+
+// loop decides the traces that timed out waiting for their local root.
+func (p *tailSamplingProcessor) loop() {
+	defer close(p.doneC)
+
+	ticker := time.NewTicker(max(p.opts.decisionTimeout/4, time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopC:
+			return
+		case <-ticker.C:
+		}
+
+		deadline := time.Now().Add(-p.opts.decisionTimeout)
+
+		p.mux.Lock()
+
+		var decided []*tailTrace
+		for p.order.Len() > 0 {
+			trace := p.order.Front().Value.(*tailTrace)
+			if trace.firstSeen.After(deadline) {
+				break
+			}
+
+			p.remove(trace)
+			decided = append(decided, trace)
+		}
+
+		for _, decisions := range []map[oteltracer.TraceID]time.Time{p.kept, p.discarded} {
+			for traceID, decidedAt := range decisions {
+				if decidedAt.Before(deadline) {
+					delete(decisions, traceID)
+				}
+			}
+		}
+
+		p.mux.Unlock()
+
+		p.decide(decided)
+	}
+}
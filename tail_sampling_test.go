@@ -0,0 +1,210 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltracer "go.opentelemetry.io/otel/trace"
+)
+
+func newTailSampledTracer(t *testing.T, tailSampling TailSamplingConfig) (Tracer, *tracetest.InMemoryExporter) {
+	tailSampling.Enabled = true
+	exporter := tracetest.NewInMemoryExporter()
+
+	shutdownFn, err := InitTraceProvider(&Config{TailSampling: tailSampling}, exporter)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = shutdownFn(context.Background()) })
+
+	tracer := newOtelTracer(&Config{})
+	t.Cleanup(tracer.Close)
+
+	return tracer, exporter
+}
+
+func TestTailSampling_KeepsNotableTraces(t *testing.T) {
+	tracer, exporter := newTailSampledTracer(t, TailSamplingConfig{
+		LatencyThreshold: 20 * time.Millisecond,
+	})
+
+	before := Stats()
+
+	failingCtx := context.Background()
+	endFailing := tracer.TraceWithName(&failingCtx, "failing")
+
+	childCtx := failingCtx
+	tracer.TraceWithName(&childCtx, "failing-child")()
+	tracer.TraceError(failingCtx, errors.New("boom"))
+	endFailing()
+
+	slowCtx := context.Background()
+	endSlow := tracer.TraceWithName(&slowCtx, "slow")
+	time.Sleep(25 * time.Millisecond)
+	endSlow()
+
+	fastCtx := context.Background()
+	tracer.TraceWithName(&fastCtx, "fast")()
+
+	flushProvider(t)
+
+	names := spanNames(exporter.GetSpans())
+	require.Contains(t, names, "failing")
+	require.Contains(t, names, "failing-child", "Expected the whole trace to be kept")
+	require.Contains(t, names, "slow")
+	require.NotContains(t, names, "fast")
+
+	stats := Stats()
+	require.EqualValues(t, 2, stats.TracesKept-before.TracesKept)
+	require.EqualValues(t, 1, stats.TracesDiscarded-before.TracesDiscarded)
+	require.Positive(t, stats.SpansDiscarded-before.SpansDiscarded)
+}
+
+func TestTailSampling_KeepsStuckTraces(t *testing.T) {
+	tracer, exporter := newTailSampledTracer(t, TailSamplingConfig{})
+	tracer.(*otelTracer).reconfigure(&Config{
+		StuckFunctionWatchdog: true,
+		StuckFunctionTimeout:  10 * time.Millisecond,
+		Logger:                slog.New(slog.DiscardHandler),
+	})
+
+	ctx := context.Background()
+	endFn := tracer.TraceWithName(&ctx, "stuck")
+
+	require.Eventually(t, func() bool {
+		return hasErrorStatus(oteltracer.SpanFromContext(ctx))
+	}, time.Second, 5*time.Millisecond)
+
+	// completes after the watchdog fired, ending Ok
+	endFn()
+	flushProvider(t)
+
+	require.Equal(t, []string{"stuck"}, spanNames(exporter.GetSpans()))
+}
+
+func TestTailSampling_Ratio(t *testing.T) {
+	tracer, exporter := newTailSampledTracer(t, TailSamplingConfig{Ratio: 1})
+
+	ctx := context.Background()
+	tracer.TraceWithName(&ctx, "fast")()
+	flushProvider(t)
+
+	require.Contains(t, spanNames(exporter.GetSpans()), "fast")
+}
+
+func TestTailSampling_RatioAfterHeadSampling(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	shutdownFn, err := InitTraceProvider(&Config{
+		Sampling:     SamplingConfig{Sampler: SamplerTraceIDRatio, Ratio: 0.5},
+		TailSampling: TailSamplingConfig{Enabled: true, Ratio: 0.5},
+	}, exporter)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = shutdownFn(context.Background()) })
+
+	tracer := newOtelTracer(&Config{})
+	t.Cleanup(tracer.Close)
+
+	const traces = 4000
+
+	for range traces {
+		ctx := context.Background()
+		tracer.TraceWithName(&ctx, "fast")()
+	}
+
+	flushProvider(t)
+
+	// a quarter is kept when the draws are independent, a half if the tail one repeated the head one
+	require.InDelta(t, traces/4, len(exporter.GetSpans()), traces/20)
+}
+
+func TestTailSampling_DecisionTimeout(t *testing.T) {
+	tracer, exporter := newTailSampledTracer(t, TailSamplingConfig{
+		DecisionTimeout: 20 * time.Millisecond,
+	})
+
+	rootCtx := context.Background()
+	endRoot := tracer.TraceWithName(&rootCtx, "root")
+
+	childCtx := rootCtx
+	endChild := tracer.TraceWithName(&childCtx, "child")
+	tracer.TraceError(childCtx, errors.New("boom"))
+	endChild()
+
+	require.Eventually(t, func() bool {
+		flushProvider(t)
+		return len(exporter.GetSpans()) > 0
+	}, time.Second, 5*time.Millisecond, "Expected the trace to be decided without its root")
+
+	require.Equal(t, []string{"child"}, spanNames(exporter.GetSpans()))
+
+	endRoot()
+	flushProvider(t)
+
+	require.Contains(t, spanNames(exporter.GetSpans()), "root", "Expected late spans to follow the decision")
+}
+
+func TestTailSampling_DiscardedLateSpans(t *testing.T) {
+	tracer, exporter := newTailSampledTracer(t, TailSamplingConfig{
+		DecisionTimeout: 20 * time.Millisecond,
+	})
+
+	before := Stats()
+
+	rootCtx := context.Background()
+	_ = tracer.TraceWithName(&rootCtx, "root")
+
+	childCtx := rootCtx
+	tracer.TraceWithName(&childCtx, "child")()
+
+	require.Eventually(t, func() bool {
+		return Stats().TracesDiscarded-before.TracesDiscarded == 1
+	}, time.Second, 5*time.Millisecond, "Expected the trace to be discarded without its root")
+
+	tracer.TraceError(rootCtx, errors.New("boom"))
+	flushProvider(t)
+
+	require.Empty(t, exporter.GetSpans(), "Expected the failed late root to follow the discard decision")
+	require.EqualValues(t, 2, Stats().SpansDiscarded-before.SpansDiscarded)
+}
+
+func TestTailSampling_Eviction(t *testing.T) {
+	tracer, exporter := newTailSampledTracer(t, TailSamplingConfig{MaxTraces: 1})
+
+	before := Stats()
+
+	firstCtx := context.Background()
+	endFirst := tracer.TraceWithName(&firstCtx, "first")
+	defer endFirst()
+
+	childCtx := firstCtx
+	endChild := tracer.TraceWithName(&childCtx, "first-child")
+	tracer.TraceError(childCtx, errors.New("boom"))
+	endChild()
+
+	secondCtx := context.Background()
+	endSecond := tracer.TraceWithName(&secondCtx, "second")
+	defer endSecond()
+
+	childCtx = secondCtx
+	tracer.TraceWithName(&childCtx, "second-child")()
+
+	flushProvider(t)
+
+	require.EqualValues(t, 1, Stats().TracesEvicted-before.TracesEvicted)
+	require.Equal(t, []string{"first-child"}, spanNames(exporter.GetSpans()),
+		"Expected the evicted trace to be decided with the spans buffered so far")
+}
+
+func TestConfig_TailSamplingOptions(t *testing.T) {
+	opts := validateConfig(&Config{TailSampling: TailSamplingConfig{Ratio: 2}}).tailSamplingOptions()
+	require.Equal(t, tailSamplingOptions{
+		ratio:           1,
+		decisionTimeout: defaultTailDecisionTimeout,
+		maxTraces:       defaultTailMaxTraces,
+		maxSpans:        defaultTailMaxSpans,
+	}, opts)
+}
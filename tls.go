@@ -0,0 +1,236 @@
+package coretracer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSConfig configures the TLS connection to the collector, used when CollectorSecureSSL is set.
+// The zero value verifies the collector against the system roots, without a client certificate.
+// Certificates and keys are given either as file paths or as PEM bytes; files are reloaded
+// on new handshakes once they change on disk, so rotated certificates are picked up.
+type TLSConfig struct {
+	// CAFile is the path to the PEM bundle of CAs to verify the collector with, instead of the system roots.
+	CAFile string
+	// CAPEM is the PEM bundle of CAs, alternative to CAFile.
+	CAPEM []byte
+	// CertFile and KeyFile are paths to the PEM client certificate and its key, for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// CertPEM and KeyPEM are the PEM client certificate and its key, alternative to the files.
+	CertPEM []byte
+	KeyPEM  []byte
+	// ServerName overrides the host name the collector certificate is verified against,
+	// defaults to the host of CollectorDSN.
+	ServerName string
+	// MinVersion is the minimum TLS version, e.g. tls.VersionTLS13. Defaults to TLS 1.2.
+	MinVersion uint16
+}
+
+// ClientTLSConfig builds the TLS config for connections to the collector.
+func (c *Config) ClientTLSConfig() (*tls.Config, error) {
+	if c == nil {
+		c = DefaultConfig()
+	}
+
+	t := c.CollectorTLS
+	if len(t.ServerName) == 0 {
+		t.ServerName = collectorHost(c.CollectorDSN)
+	}
+
+	return t.build()
+}
+
+// collectorHost returns the host part of the DSN, without the port, e.g. "10.0.0.5" for "10.0.0.5:4317".
+func collectorHost(dsn string) string {
+	if host, _, err := net.SplitHostPort(dsn); err == nil {
+		return host
+	}
+
+	return dsn
+}
+
+func (t TLSConfig) build() (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		ServerName: t.ServerName,
+		MinVersion: t.MinVersion,
+	}
+
+	if tlsCfg.MinVersion == 0 {
+		tlsCfg.MinVersion = tls.VersionTLS12
+	}
+
+	switch {
+	case len(t.CertFile) > 0 || len(t.KeyFile) > 0:
+		if len(t.CertFile) == 0 || len(t.KeyFile) == 0 {
+			return nil, errors.New("both client certificate and key files must be set")
+		}
+
+		reloader := &fileReloader[tls.Certificate]{
+			paths: []string{t.CertFile, t.KeyFile},
+			load: func() (*tls.Certificate, error) {
+				cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+				return &cert, err
+			},
+		}
+
+		if _, err := reloader.get(); err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		tlsCfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return reloader.get()
+		}
+	case len(t.CertPEM) > 0 || len(t.KeyPEM) > 0:
+		cert, err := tls.X509KeyPair(t.CertPEM, t.KeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch {
+	case len(t.CAFile) > 0:
+		reloader := &fileReloader[x509.CertPool]{
+			paths: []string{t.CAFile},
+			load: func() (*x509.CertPool, error) {
+				bundle, err := os.ReadFile(t.CAFile)
+				if err != nil {
+					return nil, err
+				}
+
+				return parseCAPool(bundle)
+			},
+		}
+
+		if _, err := reloader.get(); err != nil {
+			return nil, fmt.Errorf("failed to load CA bundle: %w", err)
+		}
+
+		// the connection state carries no server name when the collector is dialed by IP,
+		// so the name to verify against must be known upfront.
+		if len(t.ServerName) == 0 {
+			return nil, errors.New("CA file requires ServerName or CollectorDSN to verify the collector host")
+		}
+
+		// the pool can't be swapped in the stdlib config, so the chain is verified
+		// here against the latest bundle, instead of the default verification.
+		tlsCfg.InsecureSkipVerify = true
+		tlsCfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			roots, err := reloader.get()
+			if err != nil {
+				return err
+			}
+
+			return verifyServerChain(cs, roots, t.ServerName)
+		}
+	case len(t.CAPEM) > 0:
+		roots, err := parseCAPool(t.CAPEM)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsCfg.RootCAs = roots
+	}
+
+	return tlsCfg, nil
+}
+
+func parseCAPool(bundle []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.New("no CA certificates found in the PEM bundle")
+	}
+
+	return pool, nil
+}
+
+func verifyServerChain(cs tls.ConnectionState, roots *x509.CertPool, serverName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("collector presented no certificates")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+
+	return err
+}
+
+// fileReloader caches the value loaded from files, until any of them changes on disk.
+// When a rotated file fails to load, e.g. a certificate written before its key,
+// the previous value is kept until the next change.
+type fileReloader[T any] struct {
+	paths []string
+	load  func() (*T, error)
+
+	mux     sync.Mutex
+	value   *T
+	modTime time.Time
+	size    int64
+}
+
+func (r *fileReloader[T]) get() (*T, error) {
+	var (
+		modTime time.Time
+		size    int64
+	)
+
+	for _, path := range r.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return r.cached(err)
+		}
+
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+
+		size += info.Size()
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.value != nil && modTime.Equal(r.modTime) && size == r.size {
+		return r.value, nil
+	}
+
+	value, err := r.load()
+	if err != nil {
+		if r.value != nil {
+			return r.value, nil
+		}
+
+		return nil, err
+	}
+
+	r.value, r.modTime, r.size = value, modTime, size
+
+	return value, nil
+}
+
+func (r *fileReloader[T]) cached(err error) (*T, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.value != nil {
+		return r.value, nil
+	}
+
+	return nil, err
+}
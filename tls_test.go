@@ -0,0 +1,253 @@
+package coretracer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, commonName string, serial int64, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{commonName},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// newMTLSServer starts a server that requires a client certificate signed by the CA,
+// responding with the client certificate serial number.
+func newMTLSServer(t *testing.T, ca, serverCert *testCert) *httptest.Server {
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].SerialNumber.String()))
+	}))
+
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{serverCert.cert.Raw},
+			PrivateKey:  serverCert.key,
+		}},
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func clientSerial(t *testing.T, tlsCfg *tls.Config, url string) string {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg, DisableKeepAlives: true}}
+
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := make([]byte, 32)
+	n, _ := resp.Body.Read(body)
+
+	return string(body[:n])
+}
+
+func TestClientTLSConfig_MutualTLS(t *testing.T) {
+	ca := newTestCert(t, "Test CA", 1, nil)
+	srv := newMTLSServer(t, ca, newTestCert(t, "collector.internal", 2, ca))
+	clientCert := newTestCert(t, "client", 3, ca)
+
+	t.Run("PEM", func(t *testing.T) {
+		cfg := &Config{CollectorTLS: TLSConfig{
+			CAPEM:      ca.certPEM,
+			CertPEM:    clientCert.certPEM,
+			KeyPEM:     clientCert.keyPEM,
+			ServerName: "collector.internal",
+			MinVersion: tls.VersionTLS13,
+		}}
+
+		tlsCfg, err := cfg.ClientTLSConfig()
+		require.NoError(t, err)
+		require.EqualValues(t, tls.VersionTLS13, tlsCfg.MinVersion)
+		require.Equal(t, "3", clientSerial(t, tlsCfg, srv.URL))
+	})
+
+	t.Run("wrong server name", func(t *testing.T) {
+		cfg := &Config{CollectorTLS: TLSConfig{
+			CAPEM:      ca.certPEM,
+			CertPEM:    clientCert.certPEM,
+			KeyPEM:     clientCert.keyPEM,
+			ServerName: "other.internal",
+		}}
+
+		tlsCfg, err := cfg.ClientTLSConfig()
+		require.NoError(t, err)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+		_, err = client.Get(srv.URL)
+		require.ErrorContains(t, err, "other.internal")
+	})
+}
+
+func TestClientTLSConfig_ReloadsFiles(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+
+	ca := newTestCert(t, "Test CA", 1, nil)
+	srv := newMTLSServer(t, ca, newTestCert(t, "collector.internal", 2, ca))
+
+	firstCert := newTestCert(t, "client", 3, ca)
+	modTime := time.Now().Add(-time.Minute)
+
+	writeFile(t, caFile, ca.certPEM, modTime)
+	writeFile(t, certFile, firstCert.certPEM, modTime)
+	writeFile(t, keyFile, firstCert.keyPEM, modTime)
+
+	cfg := &Config{CollectorTLS: TLSConfig{
+		CAFile:     caFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: "collector.internal",
+	}}
+
+	tlsCfg, err := cfg.ClientTLSConfig()
+	require.NoError(t, err)
+	require.Equal(t, "3", clientSerial(t, tlsCfg, srv.URL))
+
+	// the certificate is rotated first, the old pair is used until the key follows
+	rotatedCert := newTestCert(t, "client", 4, ca)
+	writeFile(t, certFile, rotatedCert.certPEM, modTime.Add(time.Second))
+	require.Equal(t, "3", clientSerial(t, tlsCfg, srv.URL))
+
+	writeFile(t, keyFile, rotatedCert.keyPEM, modTime.Add(2*time.Second))
+	require.Equal(t, "4", clientSerial(t, tlsCfg, srv.URL))
+
+	// collector moves to another CA, the bundle is rotated along
+	otherCA := newTestCert(t, "Other CA", 5, nil)
+	otherSrv := newMTLSServer(t, ca, newTestCert(t, "collector.internal", 6, otherCA))
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+	_, err = client.Get(otherSrv.URL)
+	require.Error(t, err)
+
+	writeFile(t, caFile, append(append([]byte{}, ca.certPEM...), otherCA.certPEM...), modTime.Add(3*time.Second))
+	require.Equal(t, "4", clientSerial(t, tlsCfg, otherSrv.URL))
+}
+
+func TestClientTLSConfig_CAFileWithoutServerName(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+
+	ca := newTestCert(t, "Test CA", 1, nil)
+	srv := newMTLSServer(t, ca, newTestCert(t, "collector.internal", 2, ca))
+	clientCert := newTestCert(t, "client", 3, ca)
+
+	writeFile(t, caFile, ca.certPEM, time.Now())
+
+	tlsConfig := TLSConfig{
+		CAFile:  caFile,
+		CertPEM: clientCert.certPEM,
+		KeyPEM:  clientCert.keyPEM,
+	}
+
+	t.Run("no collector host", func(t *testing.T) {
+		_, err := (&Config{CollectorTLS: tlsConfig}).ClientTLSConfig()
+		require.ErrorContains(t, err, "requires ServerName")
+	})
+
+	t.Run("dialed by IP", func(t *testing.T) {
+		cfg := &Config{
+			CollectorDSN: srv.Listener.Addr().String(),
+			CollectorTLS: tlsConfig,
+		}
+
+		tlsCfg, err := cfg.ClientTLSConfig()
+		require.NoError(t, err)
+		require.Equal(t, "127.0.0.1", tlsCfg.ServerName)
+		require.Equal(t, "3", clientSerial(t, tlsCfg, srv.URL))
+	})
+
+	t.Run("collector host mismatch", func(t *testing.T) {
+		cfg := &Config{
+			CollectorDSN: "10.0.0.5:4317",
+			CollectorTLS: tlsConfig,
+		}
+
+		tlsCfg, err := cfg.ClientTLSConfig()
+		require.NoError(t, err)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+		_, err = client.Get(srv.URL)
+		require.ErrorContains(t, err, "10.0.0.5")
+	})
+}
+
+func TestClientTLSConfig_Invalid(t *testing.T) {
+	_, err := (&Config{CollectorTLS: TLSConfig{CertFile: "client.pem"}}).ClientTLSConfig()
+	require.EqualError(t, err, "both client certificate and key files must be set")
+
+	_, err = (&Config{CollectorTLS: TLSConfig{CAPEM: []byte("garbage")}}).ClientTLSConfig()
+	require.EqualError(t, err, "no CA certificates found in the PEM bundle")
+
+	_, err = (&Config{CollectorTLS: TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}}).ClientTLSConfig()
+	require.ErrorContains(t, err, "failed to load CA bundle")
+}
@@ -0,0 +1,174 @@
+package tracegrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+// UnaryClientInterceptor traces unary RPCs sent, one client span per RPC,
+// injecting the trace context into the outgoing metadata.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		if !o.traced(method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		ctx, endFn := startClientSpan(ctx, method, cc)
+
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		finishRPC(ctx, endFn, err)
+
+		return err
+	}
+}
+
+// StreamClientInterceptor traces streaming RPCs sent, one client span per RPC with an event
+// per message, injecting the trace context into the outgoing metadata. The span ends once
+// the stream is received to the end, fails, or its context is done.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		callOpts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		if !o.traced(method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+
+		ctx, endFn := startClientSpan(ctx, method, cc)
+
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			finishRPC(ctx, endFn, err)
+			return nil, err
+		}
+
+		stream := &clientStream{
+			ClientStream:  cs,
+			ctx:           ctx,
+			serverStreams: desc.ServerStreams,
+			endFn:         endFn,
+			doneC:         make(chan struct{}),
+		}
+
+		go stream.finishOnDone()
+
+		return stream, nil
+	}
+}
+
+func startClientSpan(ctx context.Context, method string, cc *grpc.ClientConn) (context.Context, coretracer.SpanEnderFn) {
+	tags := methodTags(method)
+	if cc != nil {
+		tags = tags.With(TagServerAddr, cc.Target())
+	}
+
+	ctx = coretracer.ContextWithSpanKind(ctx, coretracer.SpanKindClient)
+	endFn := coretracer.TraceWithName(&ctx, spanName(method), tags)
+
+	// the metadata of the context is shared, inject into a copy
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	coretracer.Inject(ctx, metadataCarrier(md))
+
+	return metadata.NewOutgoingContext(ctx, md), endFn
+}
+
+// clientStream adds message events and ends the span with the stream.
+type clientStream struct {
+	grpc.ClientStream
+
+	ctx           context.Context
+	serverStreams bool
+	endFn         coretracer.SpanEnderFn
+
+	sent, received atomic.Int64
+	finishOnce     sync.Once
+	doneC          chan struct{}
+}
+
+// SendMsg implements grpc.ClientStream.
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		messageEvent(s.ctx, MessageSent, s.sent.Add(1))
+	}
+
+	// the actual error is returned by RecvMsg
+	return err
+}
+
+// RecvMsg implements grpc.ClientStream.
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	switch {
+	case errors.Is(err, io.EOF):
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	default:
+		messageEvent(s.ctx, MessageReceived, s.received.Add(1))
+
+		// the only response of a client streaming RPC
+		if !s.serverStreams {
+			s.finish(nil)
+		}
+	}
+
+	return err
+}
+
+// Header implements grpc.ClientStream.
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.finish(err)
+	}
+
+	return md, err
+}
+
+// finishOnDone ends the span when the stream is abandoned, by canceling its context.
+func (s *clientStream) finishOnDone() {
+	select {
+	case <-s.ctx.Done():
+		s.finish(status.FromContextError(s.ctx.Err()).Err())
+	case <-s.doneC:
+	}
+}
+
+func (s *clientStream) finish(err error) {
+	s.finishOnce.Do(func() {
+		close(s.doneC)
+		finishRPC(s.ctx, s.endFn, err)
+	})
+}
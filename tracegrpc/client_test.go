@@ -0,0 +1,121 @@
+package tracegrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	oteltracer "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/InjectiveLabs/coretracer/coretracertest"
+)
+
+func dialTraced(t *testing.T, opts ...Option) *grpc.ClientConn {
+	return dialBufconn(t, tracedServerOpts(),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(opts...)),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(opts...)),
+	)
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	rec := coretracertest.New(t)
+	conn := dialTraced(t)
+
+	ctx := metadata.AppendToOutgoingContext(rec.Context(), "x-request-id", "42")
+	client := healthpb.NewHealthClient(conn)
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "injective.exchange"})
+	require.NoError(t, err)
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	require.Empty(t, md.Get("traceparent"), "Expected the metadata of the caller to stay intact")
+
+	spans := rec.SpansNamed(checkMethod)
+	require.Len(t, spans, 2)
+
+	clientSpan, serverSpan := spans[0], spans[1]
+	require.Equal(t, oteltracer.SpanKindClient, clientSpan.SpanKind())
+	require.Equal(t, oteltracer.SpanKindServer, serverSpan.SpanKind())
+	require.Equal(t, clientSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID(), "Expected the trace to continue on the server")
+
+	rec.Span(checkMethod).
+		IsRoot().
+		HasTag(TagService, "grpc.health.v1.Health").
+		HasTag(TagMethod, "Check").
+		HasTag(TagServerAddr, "passthrough:///bufconn").
+		HasTag(TagStatusCode, int(codes.OK)).
+		HasStatusOk().
+		HasChildCount(1)
+}
+
+func TestUnaryClientInterceptor_Error(t *testing.T) {
+	rec := coretracertest.New(t)
+	conn := dialTraced(t)
+
+	_, err := healthpb.NewHealthClient(conn).Check(rec.Context(), &healthpb.HealthCheckRequest{Service: "injective.unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	for _, span := range rec.SpansNamed(checkMethod) {
+		require.Len(t, span.Events(), 1, "Expected the error recorded by %v", span.SpanKind())
+	}
+
+	rec.Span(checkMethod).
+		HasTag(TagStatusCode, int(codes.NotFound)).
+		HasStatusError().
+		HasException("rpc error: code = NotFound desc = unknown service")
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	rec := coretracertest.New(t)
+	conn := dialTraced(t)
+
+	listServices(t, rec.Context(), conn)
+
+	spans := rec.SpansNamed("grpc.reflection.v1.ServerReflection/ServerReflectionInfo")
+	require.Len(t, spans, 2)
+
+	clientSpan, serverSpan := spans[0], spans[1]
+	require.Equal(t, oteltracer.SpanKindClient, clientSpan.SpanKind())
+	require.Equal(t, clientSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID())
+	require.Equal(t, []string{"SENT 1", "RECEIVED 1"}, messageEvents(clientSpan))
+
+	rec.Span("grpc.reflection.v1.ServerReflection/ServerReflectionInfo").
+		IsRoot().
+		HasTag(TagStatusCode, int(codes.OK)).
+		HasStatusOk()
+}
+
+func TestStreamClientInterceptor_Canceled(t *testing.T) {
+	rec := coretracertest.New(t)
+	conn := dialTraced(t, WithFilter(func(fullMethod string) bool {
+		return fullMethod == "/grpc.health.v1.Health/Watch"
+	}))
+
+	ctx, cancelFn := context.WithCancel(rec.Context())
+
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{Service: "injective.exchange"})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	cancelFn()
+
+	_, err = stream.Recv()
+	require.Equal(t, codes.Canceled, status.Code(err))
+
+	clientSpan := rec.Span("grpc.health.v1.Health/Watch").
+		IsRoot().
+		HasTag(TagStatusCode, int(codes.Canceled)).
+		HasStatusError().
+		Span()
+
+	require.Equal(t, oteltracer.SpanKindClient, clientSpan.SpanKind())
+	require.Equal(t, []string{"SENT 1", "RECEIVED 1"}, messageEvents(clientSpan))
+}
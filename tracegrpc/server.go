@@ -0,0 +1,113 @@
+package tracegrpc
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+// UnaryServerInterceptor traces unary RPCs served, one server span per RPC,
+// continuing the trace of the client when the metadata carries one.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		if !o.traced(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, endFn := startServerSpan(ctx, info.FullMethod)
+		defer func() {
+			finishServerRPC(ctx, endFn, err, recover())
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor traces streaming RPCs served, one server span per RPC with
+// an event per message, continuing the trace of the client when the metadata carries one.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		if !o.traced(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx, endFn := startServerSpan(ss.Context(), info.FullMethod)
+		defer func() {
+			finishServerRPC(ctx, endFn, err, recover())
+		}()
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func startServerSpan(ctx context.Context, fullMethod string) (context.Context, coretracer.SpanEnderFn) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = coretracer.Extract(ctx, metadataCarrier(md))
+	}
+
+	tags := methodTags(fullMethod)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		tags = tags.With(TagPeerAddr, p.Addr.String())
+	}
+
+	ctx = coretracer.ContextWithSpanKind(ctx, coretracer.SpanKindServer)
+	endFn := coretracer.TraceWithName(&ctx, spanName(fullMethod), tags)
+
+	return ctx, endFn
+}
+
+// finishServerRPC reports panics of the handler too, re-raising them.
+func finishServerRPC(ctx context.Context, endFn coretracer.SpanEnderFn, err error, panicked any) {
+	if panicked == nil {
+		finishRPC(ctx, endFn, err)
+		return
+	}
+
+	coretracer.TraceError(ctx, fmt.Errorf("panic: %v", panicked))
+	endFn()
+
+	panic(panicked)
+}
+
+// serverStream carries the context with the span and adds message events.
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx            context.Context
+	sent, received atomic.Int64
+}
+
+// Context implements grpc.ServerStream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg implements grpc.ServerStream.
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		messageEvent(s.ctx, MessageSent, s.sent.Add(1))
+	}
+
+	return err
+}
+
+// RecvMsg implements grpc.ServerStream.
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		messageEvent(s.ctx, MessageReceived, s.received.Add(1))
+	}
+
+	return err
+}
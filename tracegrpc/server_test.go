@@ -0,0 +1,170 @@
+package tracegrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltracer "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/InjectiveLabs/coretracer"
+	"github.com/InjectiveLabs/coretracer/coretracertest"
+)
+
+const checkMethod = "grpc.health.v1.Health/Check"
+
+// dialBufconn serves health and reflection services over an in-memory connection.
+func dialBufconn(t *testing.T, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("injective.exchange", healthpb.HealthCheckResponse_SERVING)
+
+	server := grpc.NewServer(serverOpts...)
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.RegisterV1(server)
+
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn", dialOpts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func tracedServerOpts(opts ...Option) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(opts...)),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(opts...)),
+	}
+}
+
+// outgoingContext carries the trace of the recorder in the metadata, like a traced client would.
+func outgoingContext(rec *coretracertest.Recorder) context.Context {
+	md := metadata.MD{}
+	coretracer.Inject(rec.Context(), metadataCarrier(md))
+
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+// listServices lists the services over the bidi stream of the reflection service.
+func listServices(t *testing.T, ctx context.Context, conn *grpc.ClientConn) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GetListServicesResponse().GetService())
+
+	require.NoError(t, stream.CloseSend())
+
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func messageEvents(span sdktrace.ReadOnlySpan) []string {
+	var events []string
+
+	for _, event := range span.Events() {
+		if event.Name != "message" {
+			continue
+		}
+
+		set := otelattribute.NewSet(event.Attributes...)
+		messageType, _ := set.Value(TagMessageType)
+		messageID, _ := set.Value(TagMessageID)
+
+		events = append(events, messageType.Emit()+" "+messageID.Emit())
+	}
+
+	return events
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	rec := coretracertest.New(t)
+	conn := dialBufconn(t, tracedServerOpts())
+
+	resp, err := healthpb.NewHealthClient(conn).Check(outgoingContext(rec), &healthpb.HealthCheckRequest{
+		Service: "injective.exchange",
+	})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	rec.Span(checkMethod).
+		IsRoot().
+		HasTag(TagSystem, "grpc").
+		HasTag(TagService, "grpc.health.v1.Health").
+		HasTag(TagMethod, "Check").
+		HasTag(TagStatusCode, int(codes.OK)).
+		HasTag(TagPeerAddr, "bufconn").
+		HasStatusOk()
+
+	require.Equal(t, oteltracer.SpanKindServer, rec.Span(checkMethod).Span().SpanKind())
+}
+
+func TestUnaryServerInterceptor_Error(t *testing.T) {
+	rec := coretracertest.New(t)
+	conn := dialBufconn(t, tracedServerOpts())
+
+	_, err := healthpb.NewHealthClient(conn).Check(outgoingContext(rec), &healthpb.HealthCheckRequest{
+		Service: "injective.unknown",
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	rec.Span(checkMethod).
+		IsRoot().
+		HasTag(TagStatusCode, int(codes.NotFound)).
+		HasStatusError().
+		HasException("rpc error: code = NotFound desc = unknown service")
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	rec := coretracertest.New(t)
+	conn := dialBufconn(t, tracedServerOpts(WithFilter(func(fullMethod string) bool {
+		return fullMethod != "/grpc.health.v1.Health/Check"
+	})))
+
+	listServices(t, outgoingContext(rec), conn)
+
+	_, err := healthpb.NewHealthClient(conn).Check(outgoingContext(rec), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	span := rec.Span("grpc.reflection.v1.ServerReflection/ServerReflectionInfo").
+		IsRoot().
+		HasTag(TagService, "grpc.reflection.v1.ServerReflection").
+		HasTag(TagStatusCode, int(codes.OK)).
+		HasStatusOk().
+		Span()
+
+	require.Equal(t, oteltracer.SpanKindServer, span.SpanKind())
+	require.Equal(t, []string{"RECEIVED 1", "SENT 1"}, messageEvents(span))
+
+	rec.NoSpan(checkMethod)
+}
@@ -0,0 +1,144 @@
+// Package tracegrpc traces gRPC servers and clients with coretracer, propagating
+// the trace context in the gRPC metadata with the propagators registered by coretracer.Enable.
+//
+//	server := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(tracegrpc.UnaryServerInterceptor()),
+//		grpc.ChainStreamInterceptor(tracegrpc.StreamServerInterceptor()),
+//	)
+//
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithChainUnaryInterceptor(tracegrpc.UnaryClientInterceptor()),
+//		grpc.WithChainStreamInterceptor(tracegrpc.StreamClientInterceptor()),
+//	)
+//
+// Spans are named by the full method without the leading slash, e.g. "grpc.health.v1.Health/Check",
+// and tagged by the OpenTelemetry RPC semantic conventions. Non-OK status codes are reported
+// with coretracer.TraceError. Streaming RPCs get an event per message sent and received.
+package tracegrpc
+
+import (
+	"context"
+	"strings"
+
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	oteltracer "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+// Tags set on gRPC spans, named by the OpenTelemetry semantic conventions.
+const (
+	TagSystem      = "rpc.system"
+	TagService     = "rpc.service"
+	TagMethod      = "rpc.method"
+	TagStatusCode  = "rpc.grpc.status_code"
+	TagServerAddr  = "server.address"
+	TagPeerAddr    = "network.peer.address"
+	TagMessageType = "rpc.message.type"
+	TagMessageID   = "rpc.message.id"
+)
+
+// Message event values of TagMessageType.
+const (
+	MessageSent     = "SENT"
+	MessageReceived = "RECEIVED"
+)
+
+// Option configures the interceptors.
+type Option func(*options)
+
+type options struct {
+	filterFn func(fullMethod string) bool
+}
+
+func newOptions(opts []Option) *options {
+	o := new(options)
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithFilter traces only the RPCs the function returns true for, e.g. to skip health checks.
+// It gets the full method, e.g. "/grpc.health.v1.Health/Check".
+func WithFilter(filterFn func(fullMethod string) bool) Option {
+	return func(o *options) {
+		o.filterFn = filterFn
+	}
+}
+
+func (o *options) traced(fullMethod string) bool {
+	return o.filterFn == nil || o.filterFn(fullMethod)
+}
+
+// spanName trims the leading slash of the full method, e.g. "/pkg.Service/Method".
+func spanName(fullMethod string) string {
+	return strings.TrimPrefix(fullMethod, "/")
+}
+
+// methodTags splits the full method into the service and the method tags.
+func methodTags(fullMethod string) coretracer.Tags {
+	tags := coretracer.NewTag(TagSystem, "grpc")
+
+	service, method, found := strings.Cut(spanName(fullMethod), "/")
+	if !found {
+		return tags.With(TagMethod, service)
+	}
+
+	return tags.With(TagService, service).With(TagMethod, method)
+}
+
+// finishRPC tags the status code of the RPC, reports the error and ends the span.
+func finishRPC(ctx context.Context, endFn coretracer.SpanEnderFn, err error) {
+	code := status.Code(err)
+	coretracer.WithTags(ctx, coretracer.NewTag(TagStatusCode, int64(code)))
+
+	if code != codes.OK {
+		coretracer.TraceError(ctx, err)
+	}
+
+	endFn()
+}
+
+// messageEvent adds the event of a streamed message to the span in ctx, ids start from 1.
+func messageEvent(ctx context.Context, messageType string, id int64) {
+	oteltracer.SpanFromContext(ctx).AddEvent("message", oteltracer.WithAttributes(
+		otelattribute.String(TagMessageType, messageType),
+		otelattribute.Int64(TagMessageID, id),
+	))
+}
+
+var _ coretracer.Carrier = metadataCarrier{}
+
+// metadataCarrier adapts gRPC metadata to coretracer.Carrier.
+type metadataCarrier metadata.MD
+
+// Get implements coretracer.Carrier.
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// Set implements coretracer.Carrier.
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys implements coretracer.Carrier.
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
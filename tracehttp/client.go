@@ -0,0 +1,86 @@
+package tracehttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// Transport traces the requests sent through the base transport, one client span per request,
+// and injects the trace context into the request headers, so the server can continue the trace.
+// The span ends once the response headers are received, failed requests and responses with
+// 4xx and 5xx status codes are reported with coretracer.TraceError.
+type Transport struct {
+	base http.RoundTripper
+	opts *options
+}
+
+// NewTransport wraps the base transport, http.DefaultTransport if nil.
+func NewTransport(base http.RoundTripper, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		base: base,
+		opts: newOptions(opts),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !t.opts.traced(r) {
+		return t.base.RoundTrip(r)
+	}
+
+	ctx := coretracer.ContextWithSpanKind(r.Context(), coretracer.SpanKindClient)
+
+	tags := coretracer.NewTags().
+		With(TagMethod, r.Method).
+		With(TagURLFull, redactedURL(r.URL)).
+		With(TagServerAddr, r.URL.Host)
+
+	if r.ContentLength > 0 {
+		tags = tags.With(TagRequestSize, r.ContentLength)
+	}
+
+	endFn := coretracer.TraceWithName(&ctx, spanName(r.Method, t.opts.route(r)), tags)
+	defer endFn()
+
+	// a RoundTripper must not modify the request
+	r = r.Clone(ctx)
+	coretracer.Inject(ctx, coretracer.HeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		coretracer.TraceError(ctx, err)
+		return nil, err
+	}
+
+	respTags := coretracer.NewTag(TagStatusCode, resp.StatusCode)
+	if resp.ContentLength >= 0 {
+		respTags = respTags.With(TagResponseSize, resp.ContentLength)
+	}
+
+	coretracer.WithTags(ctx, respTags)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		coretracer.TraceError(ctx, fmt.Errorf("HTTP %s", resp.Status))
+	}
+
+	return resp, nil
+}
+
+// redactedURL drops the query and the password, which might carry secrets.
+func redactedURL(u *url.URL) string {
+	redacted := *u
+	redacted.RawQuery = ""
+	redacted.ForceQuery = false
+	redacted.Fragment = ""
+
+	return redacted.Redacted()
+}
@@ -0,0 +1,78 @@
+package tracehttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	oteltracer "go.opentelemetry.io/otel/trace"
+
+	"github.com/InjectiveLabs/coretracer/coretracertest"
+)
+
+func TestTransport(t *testing.T) {
+	rec := coretracertest.New(t)
+
+	server := httptest.NewServer(Middleware(newOrdersMux()))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	req, err := http.NewRequestWithContext(rec.Context(), http.MethodGet, server.URL+"/orders/42?api_key=secret", nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, req.Header.Get("traceparent"), "Expected the request of the caller to stay intact")
+
+	rec.Span("GET").
+		IsRoot().
+		HasTag(TagMethod, http.MethodGet).
+		HasTag(TagURLFull, server.URL+"/orders/42").
+		HasTag(TagStatusCode, http.StatusOK).
+		HasTag(TagResponseSize, len("order")).
+		HasStatusOk().
+		HasChildCount(1)
+
+	rec.Span("GET /orders/{id}").HasParent("GET").HasStatusOk()
+
+	require.Equal(t, oteltracer.SpanKindClient, rec.Span("GET").Span().SpanKind())
+}
+
+func TestTransport_Errors(t *testing.T) {
+	rec := coretracertest.New(t)
+
+	server := httptest.NewServer(Middleware(newOrdersMux()))
+
+	client := &http.Client{Transport: NewTransport(nil, WithRouteFunc(func(r *http.Request) string {
+		return r.URL.Path
+	}))}
+
+	req, err := http.NewRequestWithContext(rec.Context(), http.MethodPost, server.URL+"/orders", nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	server.Close()
+
+	req, err = http.NewRequestWithContext(rec.Context(), http.MethodGet, server.URL+"/orders/42", nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	require.Error(t, err)
+
+	rec.Span("POST /orders").
+		IsRoot().
+		HasTag(TagStatusCode, http.StatusServiceUnavailable).
+		HasStatusError().
+		HasException("HTTP 503 Service Unavailable")
+
+	rec.Span("GET /orders/42").IsRoot().HasStatusError()
+	require.Len(t, rec.Span("GET /orders/42").Span().Events(), 1)
+}
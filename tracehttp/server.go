@@ -0,0 +1,135 @@
+package tracehttp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+
+	oteltracer "go.opentelemetry.io/otel/trace"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+// Middleware traces the requests served by the handler, one server span per request,
+// continuing the trace of the client when the request carries one. Responses with
+// 5xx status codes and panics are reported with coretracer.TraceError, panics are re-raised.
+func Middleware(next http.Handler, opts ...Option) http.Handler {
+	o := newOptions(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !o.traced(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := coretracer.Extract(r.Context(), coretracer.HeaderCarrier(r.Header))
+		ctx = coretracer.ContextWithSpanKind(ctx, coretracer.SpanKindServer)
+
+		tags := coretracer.NewTags().
+			With(TagMethod, r.Method).
+			With(TagURLPath, r.URL.Path).
+			With(TagServerAddr, r.Host)
+
+		if userAgent := r.UserAgent(); len(userAgent) > 0 {
+			tags = tags.With(TagUserAgent, userAgent)
+		}
+
+		if r.ContentLength > 0 {
+			tags = tags.With(TagRequestSize, r.ContentLength)
+		}
+
+		// named by method until the route is known
+		endFn := coretracer.TraceWithName(&ctx, spanName(r.Method, ""), tags)
+
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		r = r.WithContext(ctx)
+
+		defer func() {
+			panicked := recover()
+
+			if route := o.route(r); len(route) > 0 {
+				oteltracer.SpanFromContext(ctx).SetName(spanName(r.Method, route))
+				coretracer.WithTags(ctx, coretracer.NewTag(TagRoute, route))
+			}
+
+			statusCode := rw.statusCode
+			if panicked != nil && !rw.wroteHeader {
+				// net/http aborts the connection
+				statusCode = http.StatusInternalServerError
+			}
+
+			coretracer.WithTags(ctx, coretracer.NewTags().
+				With(TagStatusCode, statusCode).
+				With(TagResponseSize, rw.size),
+			)
+
+			switch {
+			case panicked != nil:
+				coretracer.TraceError(ctx, fmt.Errorf("panic: %v", panicked))
+			case statusCode >= http.StatusInternalServerError:
+				coretracer.TraceError(ctx, fmt.Errorf("HTTP %d %s", statusCode, http.StatusText(statusCode)))
+			}
+
+			endFn()
+
+			if panicked != nil {
+				panic(panicked)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+var (
+	_ http.Flusher  = (*responseWriter)(nil)
+	_ http.Hijacker = (*responseWriter)(nil)
+)
+
+// responseWriter records the status code and the size of the response.
+type responseWriter struct {
+	http.ResponseWriter
+
+	statusCode  int
+	wroteHeader bool
+	size        int64
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *responseWriter) WriteHeader(statusCode int) {
+	// informational responses are followed by the final one
+	if !w.wroteHeader && statusCode >= http.StatusOK {
+		w.statusCode = statusCode
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
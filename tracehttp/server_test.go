@@ -0,0 +1,126 @@
+package tracehttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	oteltracer "go.opentelemetry.io/otel/trace"
+
+	"github.com/InjectiveLabs/coretracer"
+	"github.com/InjectiveLabs/coretracer/coretracertest"
+)
+
+func newOrdersMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		defer coretracer.TraceWithName(&ctx, "loadOrder")()
+
+		_, _ = w.Write([]byte("order"))
+	})
+
+	mux.HandleFunc("POST /orders", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "matching engine is down", http.StatusServiceUnavailable)
+	})
+
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	return mux
+}
+
+func serve(ctx context.Context, handler http.Handler, method, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	coretracer.Inject(ctx, coretracer.HeaderCarrier(req.Header))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	rec := coretracertest.New(t)
+
+	handler := Middleware(newOrdersMux(), WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/healthz"
+	}))
+
+	resp := serve(rec.Context(), handler, http.MethodGet, "/orders/42")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	serve(rec.Context(), handler, http.MethodPost, "/orders")
+	serve(rec.Context(), handler, http.MethodGet, "/healthz")
+	serve(rec.Context(), handler, http.MethodGet, "/missing")
+
+	rec.Span("GET /orders/{id}").
+		IsRoot().
+		HasTag(TagMethod, http.MethodGet).
+		HasTag(TagRoute, "/orders/{id}").
+		HasTag(TagURLPath, "/orders/42").
+		HasTag(TagStatusCode, http.StatusOK).
+		HasTag(TagResponseSize, len("order")).
+		HasStatusOk().
+		HasChildCount(1)
+
+	rec.Span("loadOrder").HasParent("GET /orders/{id}")
+
+	rec.Span("POST /orders").
+		IsRoot().
+		HasTag(TagStatusCode, http.StatusServiceUnavailable).
+		HasStatusError().
+		HasException("HTTP 503 Service Unavailable")
+
+	rec.Span("GET").HasTag(TagStatusCode, http.StatusNotFound).HasStatusOk()
+	rec.NoSpan("GET /healthz")
+
+	require.Equal(t, oteltracer.SpanKindServer, rec.Span("GET /orders/{id}").Span().SpanKind())
+	require.Equal(t, oteltracer.SpanKindInternal, rec.Span("loadOrder").Span().SpanKind())
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	rec := coretracertest.New(t)
+	handler := Middleware(newOrdersMux())
+
+	require.PanicsWithValue(t, "boom", func() {
+		serve(rec.Context(), handler, http.MethodGet, "/panic")
+	})
+
+	rec.Span("GET /panic").
+		HasTag(TagStatusCode, http.StatusInternalServerError).
+		HasStatusError().
+		HasException("panic: boom")
+}
+
+func TestMiddleware_RouteFunc(t *testing.T) {
+	rec := coretracertest.New(t)
+
+	handler := Middleware(newOrdersMux(), WithRouteFunc(func(r *http.Request) string {
+		return "/v1" + patternRoute(r)
+	}))
+
+	serve(rec.Context(), handler, http.MethodGet, "/orders/42")
+
+	rec.Span("GET /v1/orders/{id}").HasTag(TagRoute, "/v1/orders/{id}")
+}
+
+func TestPatternRoute(t *testing.T) {
+	for pattern, route := range map[string]string{
+		"":                           "",
+		"/":                          "/",
+		"/orders/{id}":               "/orders/{id}",
+		"GET /orders/{id}":           "/orders/{id}",
+		"GET api.injective.network/": "/",
+		"api.injective.network/{$}":  "/{$}",
+		"DELETE  /orders/{id...}":    "/orders/{id...}",
+	} {
+		require.Equal(t, route, patternRoute(&http.Request{Pattern: pattern}), pattern)
+	}
+}
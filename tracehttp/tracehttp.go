@@ -0,0 +1,111 @@
+// Package tracehttp traces net/http servers and clients with coretracer, propagating
+// the trace context in the W3C headers registered by coretracer.Enable.
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("GET /orders/{id}", getOrder)
+//	http.ListenAndServe(":8080", tracehttp.Middleware(mux))
+//
+//	client := &http.Client{Transport: tracehttp.NewTransport(http.DefaultTransport)}
+//
+// Span names are low cardinality: the method and the route template, e.g. "GET /orders/{id}",
+// never the URL path. Tags follow the OpenTelemetry HTTP semantic conventions.
+package tracehttp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Tags set on HTTP spans, named by the OpenTelemetry semantic conventions.
+const (
+	TagMethod       = "http.request.method"
+	TagRoute        = "http.route"
+	TagStatusCode   = "http.response.status_code"
+	TagRequestSize  = "http.request.body.size"
+	TagResponseSize = "http.response.body.size"
+	TagURLPath      = "url.path"
+	TagURLFull      = "url.full"
+	TagServerAddr   = "server.address"
+	TagUserAgent    = "user_agent.original"
+)
+
+// Option configures Middleware and NewTransport.
+type Option func(*options)
+
+type options struct {
+	routeFn  func(r *http.Request) string
+	filterFn func(r *http.Request) bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		routeFn: patternRoute,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithRouteFunc sets the function resolving the route template of the request, e.g. "/orders/{id}",
+// for routers other than http.ServeMux. The middleware calls it once the request is served,
+// so routers filling the route during routing work too. Requests without a route are named by method.
+//
+// By default the http.ServeMux pattern is used, outgoing requests have none.
+func WithRouteFunc(routeFn func(r *http.Request) string) Option {
+	return func(o *options) {
+		o.routeFn = routeFn
+	}
+}
+
+// WithFilter traces only the requests the function returns true for, e.g. to skip health checks.
+func WithFilter(filterFn func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.filterFn = filterFn
+	}
+}
+
+func (o *options) traced(r *http.Request) bool {
+	return o.filterFn == nil || o.filterFn(r)
+}
+
+// spanName follows the "{method} {route}" convention, falling back to the method alone.
+func spanName(method, route string) string {
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+
+	if len(route) == 0 {
+		return method
+	}
+
+	return method + " " + route
+}
+
+// route resolves the route template, empty if unknown.
+func (o *options) route(r *http.Request) string {
+	if o.routeFn == nil {
+		return ""
+	}
+
+	return o.routeFn(r)
+}
+
+// patternRoute returns the path of the http.ServeMux pattern, e.g. "/orders/{id}" of "GET example.com/orders/{id}".
+func patternRoute(r *http.Request) string {
+	pattern := r.Pattern
+
+	// the method is separated by a space
+	if _, path, found := strings.Cut(pattern, " "); found {
+		pattern = strings.TrimSpace(path)
+	}
+
+	// the host is everything before the path
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+
+	return pattern
+}
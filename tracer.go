@@ -2,8 +2,11 @@ package coretracer
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	otel "go.opentelemetry.io/otel"
 )
@@ -12,6 +15,7 @@ var (
 	tracer             Tracer
 	tracerMux          = new(sync.RWMutex)
 	exporterShutdownFn ExporterShutdownFn
+	initRetryCancelFn  context.CancelFunc
 
 	config *Config
 )
@@ -19,6 +23,9 @@ var (
 type (
 	SpanEnderFn        func()
 	ExporterShutdownFn func(ctx context.Context) error
+	// ExporterInitFn is the error-returning counterpart of the init functions passed to Enable,
+	// see EnableE and InitExporters.
+	ExporterInitFn func(cfg *Config) (ExporterShutdownFn, error)
 )
 
 type Tracer interface {
@@ -34,27 +41,158 @@ type Tracer interface {
 }
 
 func Enable(cfg *Config, exporterInitFn func(cfg *Config) ExporterShutdownFn) {
+	cfg = validateConfig(cfg).clone()
+	ctx := startEnable()
+
+	shutdownFn, installFn, _ := runInit(cfg, func(cfg *Config) (ExporterShutdownFn, error) {
+		return exporterInitFn(cfg), nil
+	})
+
 	tracerMux.Lock()
 	defer tracerMux.Unlock()
 
-	exporterShutdownFn = exporterInitFn(cfg)
-
-	if exporterShutdownFn == nil {
+	if shutdownFn == nil {
 		slog.Warn("coretracer: failed to enable tracer")
+		finishInit(ctx)
+		return
+	}
+
+	if !finishEnable(ctx, cfg, shutdownFn, installFn) {
+		slog.Warn("coretracer: tracer was enabled again or disabled during Enable")
+	}
+}
+
+// EnableE is Enable with an init function that reports failures, e.g. InitExporters.
+// When the init fails the tracer stays disabled and the error is returned.
+// If cfg.InitRetryInterval is set, the init is retried in background until it succeeds,
+// then tracing is switched on. Disable, Close or another Enable stop the retries.
+// The init runs without blocking the tracer, so it may dial and ping the collector.
+func EnableE(cfg *Config, exporterInitFn ExporterInitFn) error {
+	cfg = validateConfig(cfg).clone()
+	ctx := startEnable()
+
+	shutdownFn, installFn, err := runInit(cfg, exporterInitFn)
+
+	tracerMux.Lock()
+	defer tracerMux.Unlock()
+
+	if err == nil {
+		if !finishEnable(ctx, cfg, shutdownFn, installFn) {
+			return errors.New("coretracer: tracer was enabled again or disabled during EnableE")
+		}
+
+		return nil
+	}
+
+	if cfg.InitRetryInterval > 0 && ctx.Err() == nil {
+		go retryInit(ctx, cfg, exporterInitFn)
+	} else {
+		finishInit(ctx)
+	}
+
+	return fmt.Errorf("coretracer: failed to enable tracer: %w", err)
+}
+
+func retryInit(ctx context.Context, cfg *Config, exporterInitFn ExporterInitFn) {
+	ticker := time.NewTicker(cfg.InitRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		shutdownFn, installFn, err := runInit(cfg, exporterInitFn)
+		if err != nil {
+			cfg.Logger.Debug("coretracer: tracer init retry failed", "error", err)
+			continue
+		}
+
+		tracerMux.Lock()
+		enabled := finishEnable(ctx, cfg, shutdownFn, installFn)
+		tracerMux.Unlock()
+
+		if enabled {
+			cfg.Logger.Info("coretracer: tracer enabled after init retry")
+		}
+
 		return
 	}
+}
+
+// startEnable stops the background init and the init in progress, if any, and returns the context
+// of the new one. It's canceled once the init is superseded by Enable, Disable or Close.
+func startEnable() context.Context {
+	tracerMux.Lock()
+	defer tracerMux.Unlock()
+
+	stopInitRetry()
 
+	ctx, cancelFn := context.WithCancel(context.Background())
+	initRetryCancelFn = cancelFn
+
+	return ctx
+}
+
+// finishEnable installs the trace provider built by the init and switches tracing on, unless the init
+// was superseded meanwhile: then the provider is never installed, its exporters are shut down
+// and false is returned. Must be called with tracerMux locked.
+func finishEnable(ctx context.Context, cfg *Config, shutdownFn ExporterShutdownFn, installFn func()) bool {
+	if ctx.Err() != nil {
+		if err := shutdownFn(context.Background()); err != nil {
+			cfg.Logger.Warn("coretracer: failed to shutdown exporter", "error", err)
+		}
+
+		return false
+	}
+
+	finishInit(ctx)
+	installFn()
+	enable(cfg, shutdownFn)
+
+	return true
+}
+
+// finishInit releases the init context, unless it was superseded. Must be called with tracerMux locked.
+func finishInit(ctx context.Context) {
+	if ctx.Err() == nil {
+		stopInitRetry()
+	}
+}
+
+// enable switches tracing on, must be called with tracerMux locked.
+func enable(cfg *Config, shutdownFn ExporterShutdownFn) {
+	exporterShutdownFn = shutdownFn
+
+	setErrorHandler(cfg)
+	registerPropagators(cfg)
+
+	tracer = newOtelTracer(cfg)
+	config = cfg.clone()
+}
+
+func setErrorHandler(cfg *Config) {
 	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
 		cfg.Logger.Warn("coretracer: otel tracer error", "error", err)
 	}))
+}
 
-	tracer = newOtelTracer(cfg)
-	config = cfg
+// stopInitRetry cancels the init in progress or the background init of EnableE,
+// must be called with tracerMux locked.
+func stopInitRetry() {
+	if initRetryCancelFn != nil {
+		initRetryCancelFn()
+		initRetryCancelFn = nil
+	}
 }
 
 func Disable() {
 	tracerMux.Lock()
 	defer tracerMux.Unlock()
+
+	stopInitRetry()
 	if tracer != nil {
 		tracer.Close()
 
@@ -146,6 +284,8 @@ func SetCallStackOffset(offset int) {
 func Close() {
 	tracerMux.Lock()
 	defer tracerMux.Unlock()
+
+	stopInitRetry()
 	if tracer == nil {
 		return
 	}
@@ -6,11 +6,13 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	otel "go.opentelemetry.io/otel"
 	otelattribute "go.opentelemetry.io/otel/attribute"
 	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	oteltracer "go.opentelemetry.io/otel/trace"
 
 	"github.com/InjectiveLabs/coretracer/stackcache"
@@ -57,12 +59,12 @@ func newOtelTracer(cfg *Config) Tracer {
 	cfg = validateConfig(cfg)
 
 	t := &otelTracer{
-		config:          cfg,
-		logger:          cfg.Logger,
 		callStackOffset: 0,
 		tracer:          otel.GetTracerProvider().Tracer("coretracer"),
 	}
 
+	t.config.Store(cfg)
+
 	t.stackCache = stackcache.New(
 		defaultStackSearchOffset,
 		t.callStackOffset,
@@ -73,13 +75,22 @@ func newOtelTracer(cfg *Config) Tracer {
 }
 
 type otelTracer struct {
-	config          *Config
+	// config is swapped by Reconfigure, spans read it once when they start.
+	config          atomic.Pointer[Config]
 	callStackOffset int
 	tracer          oteltracer.Tracer
-	logger          BasicLogger
 	stackCache      stackcache.StackCache
 }
 
+func (t *otelTracer) logger() BasicLogger {
+	return t.config.Load().Logger
+}
+
+// reconfigure switches the tracer to the config, affecting spans started afterwards.
+func (t *otelTracer) reconfigure(cfg *Config) {
+	t.config.Store(cfg)
+}
+
 // Close implements Tracer.
 func (t *otelTracer) Close() {
 	t.tracer = nil
@@ -89,8 +100,8 @@ func (t *otelTracer) Close() {
 func (t *otelTracer) Trace(ctx *context.Context, tags ...Tags) SpanEnderFn {
 	defer func() {
 		if r := recover(); r != nil {
-			t.logger.Error("coretracer: Trace() panicked - this is a bug", "panic", r)
-			t.logger.Error("coretracer: stack trace", "stack", string(debug.Stack()))
+			t.logger().Error("coretracer: Trace() panicked - this is a bug", "panic", r)
+			t.logger().Error("coretracer: stack trace", "stack", string(debug.Stack()))
 		}
 	}()
 
@@ -104,13 +115,13 @@ func (t *otelTracer) Trace(ctx *context.Context, tags ...Tags) SpanEnderFn {
 func (t *otelTracer) TraceError(ctx context.Context, err error, tags ...Tags) {
 	defer func() {
 		if r := recover(); r != nil {
-			t.logger.Error("coretracer: TraceError() panicked - this is a bug", "panic", r)
-			t.logger.Error("coretracer: stack trace", "stack", string(debug.Stack()))
+			t.logger().Error("coretracer: TraceError() panicked - this is a bug", "panic", r)
+			t.logger().Error("coretracer: stack trace", "stack", string(debug.Stack()))
 		}
 	}()
 
 	if err == nil {
-		t.logger.Debug("coretracer: TraceError() called with nil error")
+		t.logger().Debug("coretracer: TraceError() called with nil error")
 		return
 	} else if ctx == nil {
 		ctx = context.Background()
@@ -124,7 +135,7 @@ func (t *otelTracer) TraceError(ctx context.Context, err error, tags ...Tags) {
 		frame := t.stackCache.GetCaller()
 		funcName := stackcache.FuncName(frame.Function)
 
-		t.logger.Debug("coretracer: TracelessError starts from", "function", funcName)
+		t.logger().Debug("coretracer: TracelessError starts from", "function", funcName)
 
 		ctxPtr := &ctx
 		_ = t.traceStart(ctxPtr, funcName, true, tags)
@@ -135,33 +146,29 @@ func (t *otelTracer) TraceError(ctx context.Context, err error, tags ...Tags) {
 	}
 
 	span.SetStatus(otelcodes.Error, err.Error())
-	errorOpts := []oteltracer.EventOption{
-		// do not include stack trace provided by OpenTelemetry SDK,
-		// we'll set our own.
-	}
+
+	var attributes []otelattribute.KeyValue
 
 	// isNewSpan already includes these tags
 	if len(tags) > 0 && !isNewSpan {
-		// Merge tags into attributes
-		allTags := NewTags().Union(tags...)
-		attributes := make([]otelattribute.KeyValue, 0, len(tags))
-		allTags.Range(func(k string, v any) bool {
-			attributes = append(attributes, anyToOtalAttribute(k, v))
-			return true
-		})
-		errorOpts = append(errorOpts, oteltracer.WithAttributes(attributes...))
+		attributes = append(attributes, tagsToAttributes(tags)...)
 	}
 
 	// Capture and trim stack trace to exclude internal frames
 	// Skip frames: runtime.Callers(0), captureErrorStackTrace(1), TraceError-otelTracer(2)
 	stackTrace := captureErrorStackTrace(3)
 	if stackTrace != "" {
-		errorOpts = append(errorOpts, oteltracer.WithAttributes(
-			otelattribute.String("exception.stacktrace", stackTrace),
-		))
+		attributes = append(attributes, otelattribute.String("exception.stacktrace", stackTrace))
 	}
 
-	span.RecordError(err, errorOpts...)
+	if errorRecorded(span, err) {
+		// already reported, e.g. mirrored from an error log: keep a single exception event
+		// and add the tags and stack trace to the span instead
+		span.SetAttributes(attributes...)
+	} else {
+		// do not include stack trace provided by OpenTelemetry SDK, we've set our own.
+		span.RecordError(err, oteltracer.WithAttributes(attributes...))
+	}
 
 	span.End()
 }
@@ -170,8 +177,8 @@ func (t *otelTracer) TraceError(ctx context.Context, err error, tags ...Tags) {
 func (t *otelTracer) TraceWithName(ctx *context.Context, name string, tags ...Tags) SpanEnderFn {
 	defer func() {
 		if r := recover(); r != nil {
-			t.logger.Error("coretracer: TraceWithName() panicked - this is a bug", "panic", r)
-			t.logger.Error("coretracer: stack trace", "stack", string(debug.Stack()))
+			t.logger().Error("coretracer: TraceWithName() panicked - this is a bug", "panic", r)
+			t.logger().Error("coretracer: stack trace", "stack", string(debug.Stack()))
 		}
 	}()
 
@@ -182,15 +189,15 @@ func (t *otelTracer) TraceWithName(ctx *context.Context, name string, tags ...Ta
 func (t *otelTracer) Traceless(ctx *context.Context, tags ...Tags) SpanEnderFn {
 	defer func() {
 		if r := recover(); r != nil {
-			t.logger.Error("coretracer: Traceless() panicked - this is a bug", "panic", r)
-			t.logger.Error("coretracer: stack trace", "stack", string(debug.Stack()))
+			t.logger().Error("coretracer: Traceless() panicked - this is a bug", "panic", r)
+			t.logger().Error("coretracer: stack trace", "stack", string(debug.Stack()))
 		}
 	}()
 
 	frame := t.stackCache.GetCaller()
 	funcName := stackcache.FuncName(frame.Function)
 
-	t.logger.Debug("coretracer: Traceless() starts from", "function", funcName)
+	t.logger().Debug("coretracer: Traceless() starts from", "function", funcName)
 
 	return t.traceStart(ctx, funcName, true, tags)
 }
@@ -199,8 +206,8 @@ func (t *otelTracer) Traceless(ctx *context.Context, tags ...Tags) SpanEnderFn {
 func (t *otelTracer) TracelessWithName(ctx *context.Context, name string, tags ...Tags) SpanEnderFn {
 	defer func() {
 		if r := recover(); r != nil {
-			t.logger.Error("coretracer: TracelessWithName() panicked - this is a bug", "panic", r)
-			t.logger.Error("coretracer: stack trace", "stack", string(debug.Stack()))
+			t.logger().Error("coretracer: TracelessWithName() panicked - this is a bug", "panic", r)
+			t.logger().Error("coretracer: stack trace", "stack", string(debug.Stack()))
 		}
 	}()
 
@@ -215,16 +222,29 @@ func (t *otelTracer) traceStart(ctx *context.Context, funcName string, virtualTr
 		virtualTrace = true
 	}
 
-	allTags := NewTags().Union(tags...)
-	attributes := make([]otelattribute.KeyValue, 0, len(tags))
-	allTags.Range(func(k string, v any) bool {
-		attributes = append(attributes, anyToOtalAttribute(k, v))
-		return true
-	})
+	var (
+		attributes  []otelattribute.KeyValue
+		parentSpans []oteltracer.Span
+		startOpts   []oteltracer.SpanStartOption
+	)
+
+	kind, hasKind := spanKindFromContext(*ctx)
+	if hasKind {
+		startOpts = append(startOpts, oteltracer.WithSpanKind(kind))
+	}
+
+	link, hasLink := linkedRootFromContext(*ctx)
+	if hasLink {
+		startOpts = append(startOpts, oteltracer.WithNewRoot(), oteltracer.WithLinks(oteltracer.Link{SpanContext: link}))
+	}
 
-	var parentSpans []oteltracer.Span
 	parentSpansEndFn := func(spansToEnd []oteltracer.Span) {}
 
+	// passed at start so samplers see the tags, unless the span is dropped anyway
+	if virtualTrace || hasLink || !knownUnsampled(*ctx, funcName, kind) {
+		attributes = tagsToAttributes(tags)
+	}
+
 	if virtualTrace {
 		now := time.Now().UTC()
 		frames := t.stackCache.GetStackFrames()
@@ -247,14 +267,36 @@ func (t *otelTracer) traceStart(ctx *context.Context, funcName string, virtualTr
 	modifiedContext, span := t.tracer.Start(
 		*ctx,
 		funcName,
-		oteltracer.WithAttributes(attributes...),
+		append(startOpts, oteltracer.WithAttributes(attributes...))...,
 	)
 
-	doneC := make(chan struct{}, 1)
+	// children of the span are internal and continue its trace
+	if hasKind {
+		modifiedContext = context.WithValue(modifiedContext, spanKindKey{}, nil)
+	}
 
-	if t.config.StuckFunctionWatchdog {
+	if hasLink {
+		modifiedContext = context.WithValue(modifiedContext, linkedRootKey{}, nil)
+	}
+
+	if !span.IsRecording() {
+		// unsampled span: skip the watchdog,
+		// the context must still carry the span to keep children unsampled.
+		*ctx = modifiedContext
+
+		return func() {
+			parentSpansEndFn(parentSpans)
+		}
+	}
+
+	var (
+		doneC = make(chan struct{}, 1)
+		stuck atomic.Bool
+	)
+
+	if cfg := t.config.Load(); cfg.StuckFunctionWatchdog {
 		go func(name string, start time.Time) {
-			timeout := time.NewTimer(t.config.StuckFunctionTimeout)
+			timeout := time.NewTimer(cfg.StuckFunctionTimeout)
 			defer timeout.Stop()
 
 			select {
@@ -272,6 +314,7 @@ func (t *otelTracer) traceStart(ctx *context.Context, funcName string, virtualTr
 
 				span.SetAttributes(otelattribute.String("exception.type", "stuck"))
 				span.SetStatus(otelcodes.Error, "stuck")
+				stuck.Store(true)
 			}
 		}(funcName, time.Now().UTC())
 	}
@@ -283,7 +326,11 @@ func (t *otelTracer) traceStart(ctx *context.Context, funcName string, virtualTr
 		close(doneC)
 
 		if span.IsRecording() {
-			span.SetStatus(otelcodes.Ok, "")
+			// a function completing after the watchdog fired ends Ok, the error of a log is kept
+			if !hasErrorStatus(span) || stuck.Load() && statusDescription(span) == "stuck" {
+				span.SetStatus(otelcodes.Ok, "")
+			}
+
 			span.End()
 		}
 
@@ -291,6 +338,47 @@ func (t *otelTracer) traceStart(ctx *context.Context, funcName string, virtualTr
 	}
 }
 
+// hasErrorStatus tells whether the SDK span has the error status.
+func hasErrorStatus(span oteltracer.Span) bool {
+	readOnly, ok := span.(sdktrace.ReadOnlySpan)
+
+	return ok && readOnly.Status().Code == otelcodes.Error
+}
+
+// statusDescription returns the status description of the SDK span.
+func statusDescription(span oteltracer.Span) string {
+	readOnly, ok := span.(sdktrace.ReadOnlySpan)
+	if !ok {
+		return ""
+	}
+
+	return readOnly.Status().Description
+}
+
+// errorRecorded tells whether the SDK span has an exception event with the message of the error.
+func errorRecorded(span oteltracer.Span, err error) bool {
+	readOnly, ok := span.(sdktrace.ReadOnlySpan)
+	if !ok {
+		return false
+	}
+
+	message := err.Error()
+
+	for _, event := range readOnly.Events() {
+		if event.Name != "exception" {
+			continue
+		}
+
+		for _, attr := range event.Attributes {
+			if attr.Key == "exception.message" && attr.Value.AsString() == message {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func (t *otelTracer) callStackFramesToSpans(
 	timestamp time.Time,
 	frames []runtime.Frame,
@@ -336,25 +424,18 @@ func (t *otelTracer) callStackFramesToSpans(
 func (t *otelTracer) WithTags(ctx context.Context, tags ...Tags) {
 	defer func() {
 		if r := recover(); r != nil {
-			t.logger.Error("coretracer: WithTags() panicked - this is a bug", "panic", r)
-			t.logger.Error("coretracer: stack trace", "stack", string(debug.Stack()))
+			t.logger().Error("coretracer: WithTags() panicked - this is a bug", "panic", r)
+			t.logger().Error("coretracer: stack trace", "stack", string(debug.Stack()))
 		}
 	}()
 
 	span := oteltracer.SpanFromContext(ctx)
 	if span == nil {
-		t.logger.Warn("coretracer: no span found in context - WithTags() with invalid context")
+		t.logger().Warn("coretracer: no span found in context - WithTags() with invalid context")
 		return
 	}
 
-	allTags := NewTags().Union(tags...)
-	attributes := make([]otelattribute.KeyValue, 0, len(tags))
-	allTags.Range(func(k string, v any) bool {
-		attributes = append(attributes, anyToOtalAttribute(k, v))
-		return true
-	})
-
-	span.SetAttributes(attributes...)
+	span.SetAttributes(tagsToAttributes(tags)...)
 }
 
 // SetCallStackOffset implements Tracer.
@@ -366,6 +447,18 @@ func (t *otelTracer) SetCallStackOffset(offset int) {
 	t.callStackOffset = offset
 }
 
+// tagsToAttributes merges tags into a list of OTel attributes.
+func tagsToAttributes(tags []Tags) []otelattribute.KeyValue {
+	allTags := NewTags().Union(tags...)
+	attributes := make([]otelattribute.KeyValue, 0, len(tags))
+	allTags.Range(func(k string, v any) bool {
+		attributes = append(attributes, anyToOtalAttribute(k, v))
+		return true
+	})
+
+	return attributes
+}
+
 func anyToOtalAttribute(k string, v any) otelattribute.KeyValue {
 	if v == nil {
 		return otelattribute.String(k, "")
@@ -8,11 +8,15 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltracer "go.opentelemetry.io/otel/trace"
 )
 
 // TestTraceError_StackTraceExcludesInternalFrames verifies that TraceError
@@ -223,6 +227,30 @@ func TestTraceError_WithTags(t *testing.T) {
 	require.True(t, foundTag, "Expected to find test tag in error event")
 }
 
+// TestTraceStart_StuckThenCompleted verifies that a function completing after the watchdog fired ends Ok
+func TestTraceStart_StuckThenCompleted(t *testing.T) {
+	tracer, exporter := newSampledTracer(t, SamplingConfig{})
+	tracer.(*otelTracer).reconfigure(&Config{
+		EnvName:               "test",
+		StuckFunctionWatchdog: true,
+		StuckFunctionTimeout:  10 * time.Millisecond,
+		Logger:                slog.New(slog.DiscardHandler),
+	})
+
+	ctx := context.Background()
+	endFn := tracer.TraceWithName(&ctx, "SlowFunction")
+
+	require.Eventually(t, func() bool {
+		return hasErrorStatus(oteltracer.SpanFromContext(ctx))
+	}, time.Second, 5*time.Millisecond)
+
+	endFn()
+
+	span := exporter.GetSpans()[0]
+	require.Equal(t, otelcodes.Ok, span.Status.Code)
+	require.Contains(t, span.Attributes, otelattribute.String("exception.type", "stuck"))
+}
+
 // Helper functions to simulate user code with nested calls
 func userFunction1(tracer Tracer, ctx context.Context, err error) {
 	userFunction2(tracer, ctx, err)
@@ -236,3 +264,17 @@ func userFunction3(tracer Tracer, ctx context.Context, err error) {
 	// This is where TraceError is actually called
 	tracer.TraceError(ctx, err)
 }
+
+func TestContextWithSpanKind(t *testing.T) {
+	tracer, exporter := newSampledTracer(t, SamplingConfig{})
+
+	ctx := ContextWithSpanKind(context.Background(), SpanKindServer)
+	endFn := tracer.TraceWithName(&ctx, "HandleOrder")
+	tracer.TraceWithName(&ctx, "PlaceOrder")()
+	endFn()
+
+	spans := exporter.GetSpans()
+	require.Equal(t, []string{"PlaceOrder", "HandleOrder"}, spanNames(spans))
+	require.Equal(t, SpanKindInternal, spans[0].SpanKind, "Expected children not to inherit the kind")
+	require.Equal(t, SpanKindServer, spans[1].SpanKind)
+}
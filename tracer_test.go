@@ -0,0 +1,163 @@
+package coretracer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	otel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// reachableExporter is reachable once up is set.
+type reachableExporter struct {
+	*tracetest.InMemoryExporter
+	up    atomic.Bool
+	pings atomic.Int32
+}
+
+func (e *reachableExporter) Ping(ctx context.Context) error {
+	e.pings.Add(1)
+
+	if !e.up.Load() {
+		return errors.New("connection refused")
+	}
+
+	return nil
+}
+
+func TestEnableE(t *testing.T) {
+	t.Cleanup(Disable)
+
+	exporter := &reachableExporter{InMemoryExporter: tracetest.NewInMemoryExporter()}
+	exporter.up.Store(true)
+
+	err := EnableE(&Config{ServiceName: "enable-e"}, InitExporters(exporterFn(exporter)))
+	require.NoError(t, err)
+	require.NotNil(t, DefaultTracer())
+	require.EqualValues(t, 1, exporter.pings.Load())
+
+	ctx := context.Background()
+	TraceWithName(&ctx, "enabled")()
+	flushProvider(t)
+
+	require.Equal(t, []string{"enabled"}, spanNames(exporter.GetSpans()))
+}
+
+func TestEnableE_ReportsFailures(t *testing.T) {
+	t.Cleanup(Disable)
+	Disable()
+
+	err := EnableE(&Config{}, InitExporters(func(cfg *Config) (sdktrace.SpanExporter, error) {
+		return nil, errors.New("invalid collector DSN")
+	}))
+	require.EqualError(t, err, "coretracer: failed to enable tracer: failed to create exporter 0: invalid collector DSN")
+	require.Nil(t, DefaultTracer())
+
+	unreachable := &reachableExporter{InMemoryExporter: tracetest.NewInMemoryExporter()}
+	err = EnableE(&Config{}, InitExporters(exporterFn(unreachable)))
+	require.ErrorContains(t, err, "exporter 0 can't reach its destination: connection refused")
+	require.Nil(t, DefaultTracer())
+
+	err = EnableE(&Config{
+		ResourceDetectors: []resource.Detector{
+			resource.StringDetector("", "cloud.zone", func() (string, error) {
+				return "", errors.New("metadata service is down")
+			}),
+		},
+	}, InitExporters(exporterFn(tracetest.NewInMemoryExporter())))
+	require.ErrorContains(t, err, "failed to detect resource")
+	require.Nil(t, DefaultTracer())
+}
+
+func TestEnableE_RetriesInBackground(t *testing.T) {
+	t.Cleanup(Disable)
+
+	exporter := &reachableExporter{InMemoryExporter: tracetest.NewInMemoryExporter()}
+
+	err := EnableE(&Config{InitRetryInterval: 10 * time.Millisecond}, InitExporters(exporterFn(exporter)))
+	require.Error(t, err)
+	require.Nil(t, DefaultTracer())
+
+	require.Eventually(t, func() bool {
+		return exporter.pings.Load() > 2
+	}, time.Second, time.Millisecond, "Expected init to be retried")
+	require.Nil(t, DefaultTracer())
+
+	exporter.up.Store(true)
+
+	require.Eventually(t, func() bool {
+		return DefaultTracer() != nil
+	}, time.Second, time.Millisecond, "Expected tracing to be switched on once the collector is up")
+
+	pings := exporter.pings.Load()
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, pings, exporter.pings.Load(), "Expected retries to stop")
+}
+
+func TestEnableE_DisableStopsRetries(t *testing.T) {
+	exporter := &reachableExporter{InMemoryExporter: tracetest.NewInMemoryExporter()}
+
+	err := EnableE(&Config{InitRetryInterval: 10 * time.Millisecond}, InitExporters(exporterFn(exporter)))
+	require.Error(t, err)
+
+	require.Eventually(t, func() bool {
+		return exporter.pings.Load() > 1
+	}, time.Second, time.Millisecond)
+
+	Disable()
+
+	// a retry might be in flight while disabling
+	time.Sleep(20 * time.Millisecond)
+	pings := exporter.pings.Load()
+
+	exporter.up.Store(true)
+	time.Sleep(50 * time.Millisecond)
+
+	require.Equal(t, pings, exporter.pings.Load())
+	require.Nil(t, DefaultTracer())
+}
+
+func TestEnableE_InitRunsUnlocked(t *testing.T) {
+	t.Cleanup(Disable)
+	Disable()
+
+	providerBefore := otel.GetTracerProvider()
+
+	initStartedC := make(chan struct{})
+	releaseInitC := make(chan struct{})
+	errC := make(chan error, 1)
+
+	var shutdowns atomic.Int32
+
+	go func() {
+		errC <- EnableE(&Config{}, func(cfg *Config) (ExporterShutdownFn, error) {
+			shutdownFn, err := InitTraceProvider(cfg, tracetest.NewInMemoryExporter())
+			close(initStartedC)
+			<-releaseInitC
+
+			return func(ctx context.Context) error {
+				shutdowns.Add(1)
+				return shutdownFn(ctx)
+			}, err
+		})
+	}()
+
+	<-initStartedC
+
+	// the tracer is usable and can be disabled while the init is waiting for the collector
+	require.Nil(t, DefaultTracer())
+	Disable()
+
+	close(releaseInitC)
+
+	require.ErrorContains(t, <-errC, "enabled again or disabled during EnableE")
+	require.Nil(t, DefaultTracer())
+	require.Same(t, providerBefore, otel.GetTracerProvider(), "Expected the superseded provider not to be installed")
+	require.EqualValues(t, 1, shutdowns.Load(), "Expected the superseded exporters to be shut down")
+}
@@ -0,0 +1,218 @@
+package tracesql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+var (
+	_ driver.Conn               = (*tracedConn)(nil)
+	_ driver.ConnBeginTx        = (*tracedConn)(nil)
+	_ driver.ConnPrepareContext = (*tracedConn)(nil)
+	_ driver.ExecerContext      = (*tracedConn)(nil)
+	_ driver.QueryerContext     = (*tracedConn)(nil)
+	_ driver.Pinger             = (*tracedConn)(nil)
+	_ driver.SessionResetter    = (*tracedConn)(nil)
+	_ driver.Validator          = (*tracedConn)(nil)
+	_ driver.NamedValueChecker  = (*tracedConn)(nil)
+)
+
+// tracedConn traces the calls of the connection. Optional interfaces the driver connection
+// doesn't implement behave like database/sql does without them.
+type tracedConn struct {
+	conn driver.Conn
+	opts *options
+}
+
+// Prepare implements driver.Conn.
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext.
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	if ctx, endFn, ok := c.opts.start(ctx, OperationPrepare, SpanPrepare, query, nil); ok {
+		defer func() { finish(ctx, endFn, err) }()
+	}
+
+	if connCtx, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = connCtx.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedStmt{stmt: stmt, conn: c.conn, query: query, opts: c.opts}, nil
+}
+
+// Close implements driver.Conn.
+func (c *tracedConn) Close() error {
+	return c.conn.Close()
+}
+
+// Begin implements driver.Conn.
+func (c *tracedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx, the span lasts until the commit or the rollback.
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	ctx, endFn, traced := c.opts.start(ctx, OperationTransaction, SpanTransaction, "", nil)
+
+	var (
+		tx  driver.Tx
+		err error
+	)
+
+	if connBeginTx, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = connBeginTx.BeginTx(ctx, opts)
+	} else {
+		tx, err = beginLegacy(c.conn, opts)
+	}
+
+	if !traced {
+		return tx, err
+	}
+
+	if err != nil {
+		finish(ctx, endFn, err)
+		return nil, err
+	}
+
+	return &tracedTx{tx: tx, ctx: ctx, endFn: endFn}, nil
+}
+
+// beginLegacy begins the transaction on the connection without BeginTx, rejecting the options
+// it can't apply with the errors database/sql returns then.
+func beginLegacy(conn driver.Conn, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+
+	//nolint:staticcheck // database/sql falls back to Begin in the same way
+	return conn.Begin()
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	execer, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		// database/sql prepares the statement then
+		return nil, driver.ErrSkip
+	}
+
+	ctx, endFn, traced := c.opts.start(ctx, OperationExec, SpanExec, query, args)
+
+	result, err = execer.ExecContext(ctx, query, args)
+
+	if traced {
+		tagRowsAffected(ctx, result, err)
+		finish(ctx, endFn, err)
+	}
+
+	return result, err
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	queryer, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		// database/sql prepares the statement then
+		return nil, driver.ErrSkip
+	}
+
+	if ctx, endFn, ok := c.opts.start(ctx, OperationQuery, SpanQuery, query, args); ok {
+		defer func() { finish(ctx, endFn, err) }()
+	}
+
+	return queryer.QueryContext(ctx, query, args)
+}
+
+// Ping implements driver.Pinger.
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter.
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+// IsValid implements driver.Validator.
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+
+	return true
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (c *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+
+	// database/sql applies the default conversion then
+	return driver.ErrSkip
+}
+
+var _ driver.Tx = (*tracedTx)(nil)
+
+// tracedTx ends the transaction span, started by BeginTx.
+type tracedTx struct {
+	tx    driver.Tx
+	ctx   context.Context
+	endFn coretracer.SpanEnderFn
+}
+
+// Commit implements driver.Tx.
+func (t *tracedTx) Commit() error {
+	err := t.tx.Commit()
+	t.finish("commit", err)
+
+	return err
+}
+
+// Rollback implements driver.Tx.
+func (t *tracedTx) Rollback() error {
+	err := t.tx.Rollback()
+	t.finish("rollback", err)
+
+	return err
+}
+
+func (t *tracedTx) finish(outcome string, err error) {
+	coretracer.WithTags(t.ctx, coretracer.NewTag(TagTxOutcome, outcome))
+	finish(t.ctx, t.endFn, err)
+}
+
+// tagRowsAffected tags the number of rows changed by the statement, if the driver reports it.
+func tagRowsAffected(ctx context.Context, result driver.Result, err error) {
+	if err != nil || result == nil {
+		return
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err == nil {
+		coretracer.WithTags(ctx, coretracer.NewTag(TagRowsAffected, rowsAffected))
+	}
+}
@@ -0,0 +1,104 @@
+package tracesql
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+var (
+	_ driver.Driver        = (*tracedDriver)(nil)
+	_ driver.DriverContext = (*tracedDriver)(nil)
+	_ driver.Connector     = (*tracedConnector)(nil)
+)
+
+// Wrap wraps the driver to trace the calls, e.g. to register it under another name:
+//
+//	sql.Register("postgres-traced", tracesql.Wrap(&pq.Driver{}))
+func Wrap(d driver.Driver, opts ...Option) driver.Driver {
+	return &tracedDriver{
+		driver: d,
+		opts:   newOptions(opts),
+	}
+}
+
+// WrapConnector wraps the connector to trace the calls, for sql.OpenDB.
+func WrapConnector(connector driver.Connector, opts ...Option) driver.Connector {
+	o := newOptions(opts)
+
+	return &tracedConnector{
+		connector: connector,
+		driver:    &tracedDriver{driver: connector.Driver(), opts: o},
+		opts:      o,
+	}
+}
+
+type tracedDriver struct {
+	driver driver.Driver
+	opts   *options
+}
+
+// Open implements driver.Driver.
+func (d *tracedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedConn{conn: conn, opts: d.opts}, nil
+}
+
+// OpenConnector implements driver.DriverContext.
+func (d *tracedDriver) OpenConnector(name string) (driver.Connector, error) {
+	driverCtx, ok := d.driver.(driver.DriverContext)
+	if !ok {
+		return &tracedConnector{
+			connector: dsnConnector{dsn: name, driver: d.driver},
+			driver:    d,
+			opts:      d.opts,
+		}, nil
+	}
+
+	connector, err := driverCtx.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedConnector{connector: connector, driver: d, opts: d.opts}, nil
+}
+
+type tracedConnector struct {
+	connector driver.Connector
+	driver    *tracedDriver
+	opts      *options
+}
+
+// Connect implements driver.Connector.
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedConn{conn: conn, opts: c.opts}, nil
+}
+
+// Driver implements driver.Connector.
+func (c *tracedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// dsnConnector is the connector of drivers without driver.DriverContext, like the one of sql.Open.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect implements driver.Connector.
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver implements driver.Connector.
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
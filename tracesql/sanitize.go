@@ -0,0 +1,248 @@
+package tracesql
+
+import "strings"
+
+// Sanitize strips the literals from the SQL statement, so it's safe to be recorded and has low cardinality:
+// strings and numbers become "?", comments are removed and whitespace is collapsed, e.g.
+//
+//	SELECT * FROM orders WHERE id = 42 AND market = 'INJ/USDT' -- hot path
+//
+// becomes
+//
+//	SELECT * FROM orders WHERE id = ? AND market = ?
+//
+// Placeholders like $1, ? and :name, as well as backquoted identifiers, are kept. The dialect is unknown here,
+// so double-quoted text is stripped as well, being a string in MySQL, and strings with a backslash before
+// a quote are stripped up to where both readings of the backslash agree, the end of the statement at worst;
+// spans of drivers wrapped with WithSystem keep the double-quoted identifiers of the databases that have them.
+func Sanitize(query string) string {
+	return sanitize(query, dialectOf(""))
+}
+
+// dialect holds the quoting rules that differ between databases.
+type dialect struct {
+	// backslashEscapes makes backslash escape the next char in strings, as MySQL does by default.
+	// Elsewhere only PostgreSQL E'...' strings have escapes, e.g. 'C:\' is a complete string.
+	backslashEscapes bool
+	// unknownEscapes strips strings read either way, for when it's unknown whether backslash escapes.
+	unknownEscapes bool
+	// quotedIdentifiers keeps double-quoted text, which is an identifier in standard SQL
+	// but a string in MySQL.
+	quotedIdentifiers bool
+	// dollarQuotes strips PostgreSQL dollar-quoted strings, e.g. $$text$$ or $tag$text$tag$.
+	dollarQuotes bool
+}
+
+// dialectOf returns the quoting rules of the db.system.name, the ones stripping the most when it's unknown.
+func dialectOf(system string) dialect {
+	switch system {
+	case "mysql", "mariadb":
+		return dialect{backslashEscapes: true}
+	case "":
+		return dialect{unknownEscapes: true, dollarQuotes: true}
+	default:
+		return dialect{quotedIdentifiers: true, dollarQuotes: true}
+	}
+}
+
+func sanitize(query string, d dialect) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	// pending whitespace is written once, before the next token
+	space := false
+
+	write := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+
+		space = false
+		b.WriteString(s)
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+
+			space = true
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 2
+			} else {
+				end += 2
+			}
+
+			space = true
+			i += end + 2
+		case c == '\'':
+			write("?")
+			i = d.skipString(query, i, '\'')
+		case c == '"' && !d.quotedIdentifiers:
+			write("?")
+			i = d.skipString(query, i, '"')
+		case c == '"' || c == '`':
+			// quoted identifiers
+			end := skipQuoted(query, i, c, false)
+			write(query[i:end])
+			i = end
+		case c == '$' && d.dollarQuotes && dollarTag(query, i) != "":
+			write("?")
+			i = skipDollarQuoted(query, i, dollarTag(query, i))
+		case isDigit(c) && !isWordEnd(query, i):
+			write("?")
+			i = skipNumber(query, i)
+		case isWord(c) || c == '$' || c == ':' || c == '@':
+			// identifiers, keywords and placeholders
+			end := i + 1
+			for end < len(query) && (isWord(query[end]) || isDigit(query[end])) {
+				end++
+			}
+
+			if end == i+1 && (c == 'E' || c == 'e') && end < len(query) && query[end] == '\'' {
+				// PostgreSQL string with escapes, e.g. E'it\'s'
+				write("?")
+				i = skipQuoted(query, end, '\'', true)
+				continue
+			}
+
+			write(query[i:end])
+			i = end
+		default:
+			write(query[i : i+1])
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// skipString returns the index after the string at i. When escapes are unknown and the string ends
+// elsewhere with backslash escapes, both readings are followed until they are out of strings at the same index.
+func (d dialect) skipString(query string, i int, quote byte) int {
+	if !d.unknownEscapes {
+		return skipQuoted(query, i, quote, d.backslashEscapes)
+	}
+
+	plain, escaped := skipQuoted(query, i, quote, false), skipQuoted(query, i, quote, true)
+
+	for plain != escaped {
+		// move the reading behind to its next string, unless it is out of strings up to the other one
+		behind, ahead, escapes := &plain, escaped, false
+		if escaped < plain {
+			behind, ahead, escapes = &escaped, plain, true
+		}
+
+		next := strings.IndexByte(query[*behind:], quote)
+		if next < 0 {
+			return len(query)
+		}
+
+		if *behind+next >= ahead {
+			return ahead
+		}
+
+		*behind = skipQuoted(query, *behind+next, quote, escapes)
+	}
+
+	return plain
+}
+
+// skipQuoted returns the index after the closing quote, doubled quotes are escapes,
+// as well as backslashes when enabled.
+func skipQuoted(query string, i int, quote byte, backslashEscapes bool) int {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+
+			return i + 1
+		}
+	}
+
+	return len(query)
+}
+
+// dollarTag returns the opening delimiter of the dollar-quoted string at i, e.g. "$$" or "$tag$",
+// empty for anything else, like the $1 placeholder.
+func dollarTag(query string, i int) string {
+	end := i + 1
+	for end < len(query) && (isWord(query[end]) || end > i+1 && isDigit(query[end])) {
+		end++
+	}
+
+	if end < len(query) && query[end] == '$' {
+		return query[i : end+1]
+	}
+
+	return ""
+}
+
+// skipDollarQuoted returns the index after the closing delimiter, the end of the query when there is none.
+func skipDollarQuoted(query string, i int, tag string) int {
+	i += len(tag)
+
+	end := strings.Index(query[i:], tag)
+	if end < 0 {
+		return len(query)
+	}
+
+	return i + end + len(tag)
+}
+
+// skipNumber returns the index after the number, decimals, exponents and hex numbers included.
+func skipNumber(query string, i int) int {
+	if strings.HasPrefix(query[i:], "0x") || strings.HasPrefix(query[i:], "0X") {
+		i += 2
+	}
+
+	for ; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case isDigit(c) || c == '.' || isWord(c):
+		case (c == '+' || c == '-') && (query[i-1] == 'e' || query[i-1] == 'E'):
+		default:
+			return i
+		}
+	}
+
+	return i
+}
+
+// isWordEnd tells whether the digit at i continues an identifier or a placeholder, e.g. t1 or $1.
+func isWordEnd(query string, i int) bool {
+	if i == 0 {
+		return false
+	}
+
+	prev := query[i-1]
+
+	return isWord(prev) || isDigit(prev) || prev == '$' || prev == ':' || prev == '@'
+}
+
+func isWord(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,72 @@
+package tracesql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitize(t *testing.T) {
+	testCases := map[string]string{
+		"SELECT * FROM orders WHERE id = 42":                                 "SELECT * FROM orders WHERE id = ?",
+		"SELECT * FROM orders WHERE market = 'INJ/USDT' -- hot path\n":       "SELECT * FROM orders WHERE market = ?",
+		"SELECT * FROM orders WHERE note = 'it''s' AND price > -1.5e-3":      "SELECT * FROM orders WHERE note = ? AND price > -?",
+		"SELECT /* by id */ *\n\tFROM  orders\n WHERE id IN (1, 2, 0xFF)":    "SELECT * FROM orders WHERE id IN (?, ?, ?)",
+		`SELECT "order 1".id, t2.x FROM "order 1" JOIN t2 ON t2.id = $1`:     `SELECT ?.id, t2.x FROM ? JOIN t2 ON t2.id = $1`,
+		"UPDATE orders SET price = :price, qty = @qty WHERE id = ? LIMIT 10": "UPDATE orders SET price = :price, qty = @qty WHERE id = ? LIMIT ?",
+		"INSERT INTO `fills` (id) VALUES (7)":                                "INSERT INTO `fills` (id) VALUES (?)",
+		"SELECT 'unterminated":                                               "SELECT ?",
+		`INSERT INTO users VALUES ('C:\', 'hunter2-secret')`:                 "INSERT INTO users VALUES (?",
+		`SELECT * FROM users WHERE a = 'it\'s' AND pw = 'hunter2'`:           "SELECT * FROM users WHERE a = ?",
+		`SELECT * FROM users WHERE a = "it\"s" AND pw = "hunter2"`:           "SELECT * FROM users WHERE a = ?",
+		`SELECT 'a\'', '\'' FROM t`:                                          "SELECT ? FROM t",
+		`SELECT 'a\\b', 'c' FROM t`:                                          "SELECT ?, ? FROM t",
+		`SELECT $$my secret$$, $tag$it's $$ secret$tag$ FROM t WHERE a = $1`: "SELECT ?, ? FROM t WHERE a = $1",
+		`SELECT E'it\'s secret', 'a' FROM t`:                                 "SELECT ?, ? FROM t",
+		"SELECT $$unterminated secret":                                       "SELECT ?",
+		"":                                                                   "",
+	}
+
+	for query, expected := range testCases {
+		require.Equal(t, expected, Sanitize(query), query)
+	}
+}
+
+func TestSanitize_Dialects(t *testing.T) {
+	testCases := []struct {
+		system   string
+		query    string
+		expected string
+	}{
+		{
+			system:   "postgresql",
+			query:    `SELECT "order 1".id, t2.x FROM "order 1" JOIN t2 ON t2.id = $1`,
+			expected: `SELECT "order 1".id, t2.x FROM "order 1" JOIN t2 ON t2.id = $1`,
+		},
+		{
+			system:   "postgresql",
+			query:    `INSERT INTO users VALUES ('C:\', 'hunter2-secret')`,
+			expected: "INSERT INTO users VALUES (?, ?)",
+		},
+		{
+			system:   "mysql",
+			query:    `INSERT INTO users VALUES ("hunter2-secret", 'it\'s secret', 'C:\\')`,
+			expected: "INSERT INTO users VALUES (?, ?, ?)",
+		},
+		{
+			system:   "mysql",
+			query:    "SELECT `order`.id FROM `order` WHERE note = \"it\\\"s secret\"",
+			expected: "SELECT `order`.id FROM `order` WHERE note = ?",
+		},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, sanitize(tc.query, dialectOf(tc.system)), tc.system+": "+tc.query)
+	}
+}
+
+func TestFirstKeyword(t *testing.T) {
+	require.Equal(t, "SELECT", firstKeyword("select * FROM orders"))
+	require.Equal(t, "SELECT", firstKeyword("(SELECT 1) UNION (SELECT 2)"))
+	require.Equal(t, "", firstKeyword("?"))
+}
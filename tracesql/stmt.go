@@ -0,0 +1,120 @@
+package tracesql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+var (
+	_ driver.Stmt              = (*tracedStmt)(nil)
+	_ driver.StmtExecContext   = (*tracedStmt)(nil)
+	_ driver.StmtQueryContext  = (*tracedStmt)(nil)
+	_ driver.NamedValueChecker = (*tracedStmt)(nil)
+)
+
+// tracedStmt traces the executions of a prepared statement, like the ones of the connection.
+type tracedStmt struct {
+	stmt  driver.Stmt
+	conn  driver.Conn
+	query string
+	opts  *options
+}
+
+// Close implements driver.Stmt.
+func (s *tracedStmt) Close() error {
+	return s.stmt.Close()
+}
+
+// NumInput implements driver.Stmt.
+func (s *tracedStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+// Exec implements driver.Stmt.
+func (s *tracedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+// Query implements driver.Stmt.
+func (s *tracedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+// ExecContext implements driver.StmtExecContext.
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	ctx, endFn, traced := s.opts.start(ctx, OperationExec, SpanExec, s.query, args)
+
+	if stmtCtx, ok := s.stmt.(driver.StmtExecContext); ok {
+		result, err = stmtCtx.ExecContext(ctx, args)
+	} else if values, valuesErr := driverValues(args); valuesErr != nil {
+		err = valuesErr
+	} else {
+		//nolint:staticcheck // database/sql falls back to Exec in the same way
+		result, err = s.stmt.Exec(values)
+	}
+
+	if traced {
+		tagRowsAffected(ctx, result, err)
+		finish(ctx, endFn, err)
+	}
+
+	return result, err
+}
+
+// QueryContext implements driver.StmtQueryContext.
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	if ctx, endFn, ok := s.opts.start(ctx, OperationQuery, SpanQuery, s.query, args); ok {
+		defer func() { finish(ctx, endFn, err) }()
+	}
+
+	if stmtCtx, ok := s.stmt.(driver.StmtQueryContext); ok {
+		return stmtCtx.QueryContext(ctx, args)
+	}
+
+	values, err := driverValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:staticcheck // database/sql falls back to Query in the same way
+	return s.stmt.Query(values)
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (s *tracedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+
+	// database/sql asks the connection when the statement is not a checker
+	if checker, ok := s.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+
+	return driver.ErrSkip
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+
+	return named
+}
+
+// driverValues drops the ordinals, drivers without context support don't accept named arguments.
+func driverValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+
+	for i, arg := range args {
+		if len(arg.Name) > 0 {
+			return nil, errors.New("tracesql: driver does not support the use of named parameters")
+		}
+
+		values[i] = arg.Value
+	}
+
+	return values, nil
+}
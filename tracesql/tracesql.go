@@ -0,0 +1,198 @@
+// Package tracesql traces database/sql queries with coretracer, by wrapping the driver.
+// Every query, exec, prepare and transaction run with a traced context gets a child span,
+// tagged with the statement stripped of literals:
+//
+//	db, err := tracesql.Open("postgres", dsn, tracesql.WithSystem("postgresql"))
+//
+//	defer coretracer.Trace(&ctx)()
+//	rows, err := db.QueryContext(ctx, "SELECT * FROM orders WHERE id = $1", id)
+//
+// Statements run without a span in the context are not traced, so background work
+// like connection pool maintenance doesn't start root traces.
+package tracesql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	oteltracer "go.opentelemetry.io/otel/trace"
+
+	"github.com/InjectiveLabs/coretracer"
+)
+
+// Operation is a kind of database call to trace.
+type Operation string
+
+// Operations traced by default.
+const (
+	OperationQuery       Operation = "query"
+	OperationExec        Operation = "exec"
+	OperationPrepare     Operation = "prepare"
+	OperationTransaction Operation = "transaction"
+)
+
+// Span names of the operations.
+const (
+	SpanQuery       = "sql.Query"
+	SpanExec        = "sql.Exec"
+	SpanPrepare     = "sql.Prepare"
+	SpanTransaction = "sql.Tx"
+)
+
+// Tags set on database spans, named by the OpenTelemetry semantic conventions where there is one.
+const (
+	TagSystem       = "db.system.name"
+	TagStatement    = "db.query.text"
+	TagOperation    = "db.operation.name"
+	TagRowsAffected = "db.rows_affected"
+	TagTxOutcome    = "db.transaction.outcome"
+	// TagArgPrefix prefixes the arguments, e.g. "db.query.parameter.1" or "db.query.parameter.id" for named ones.
+	TagArgPrefix = "db.query.parameter."
+)
+
+// Option configures the wrapped driver.
+type Option func(*options)
+
+type options struct {
+	system     string
+	operations []Operation
+	recordArgs bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		operations: []Operation{OperationQuery, OperationExec, OperationPrepare, OperationTransaction},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithSystem sets the db.system.name tag, e.g. "postgresql" or "mysql". It also selects the quoting rules
+// statements are sanitized with, e.g. MySQL backslash escapes; see Sanitize for the defaults.
+func WithSystem(system string) Option {
+	return func(o *options) {
+		o.system = system
+	}
+}
+
+// WithOperations traces the listed operations only, all of them by default.
+func WithOperations(operations ...Operation) Option {
+	return func(o *options) {
+		o.operations = operations
+	}
+}
+
+// WithArgs records the statement arguments as tags. Arguments are recorded as is,
+// enable it only when they carry no secrets or personal data.
+func WithArgs() Option {
+	return func(o *options) {
+		o.recordArgs = true
+	}
+}
+
+// Open opens the database with the registered driver, wrapped to trace the calls:
+//
+//	db, err := tracesql.Open("postgres", dsn)
+//
+// Like sql.Open, it doesn't connect to the database.
+func Open(driverName, dataSourceName string, opts ...Option) (*sql.DB, error) {
+	// sql.Open only looks the driver up
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	d := db.Driver()
+	_ = db.Close()
+
+	if driverCtx, ok := d.(driver.DriverContext); ok {
+		connector, err := driverCtx.OpenConnector(dataSourceName)
+		if err != nil {
+			return nil, err
+		}
+
+		return sql.OpenDB(WrapConnector(connector, opts...)), nil
+	}
+
+	return sql.OpenDB(WrapConnector(dsnConnector{dsn: dataSourceName, driver: d}, opts...)), nil
+}
+
+// traced tells whether the operation is enabled and ctx carries a span to start a child of.
+func (o *options) traced(ctx context.Context, operation Operation) bool {
+	return slices.Contains(o.operations, operation) && oteltracer.SpanContextFromContext(ctx).IsValid()
+}
+
+// start starts the span of the operation, if traced.
+func (o *options) start(
+	ctx context.Context,
+	operation Operation,
+	name, query string,
+	args []driver.NamedValue,
+) (context.Context, coretracer.SpanEnderFn, bool) {
+	if !o.traced(ctx, operation) {
+		return ctx, nil, false
+	}
+
+	tags := coretracer.NewTags()
+
+	if len(o.system) > 0 {
+		tags = tags.With(TagSystem, o.system)
+	}
+
+	if len(query) > 0 {
+		statement := sanitize(query, dialectOf(o.system))
+		tags = tags.With(TagStatement, statement)
+
+		if keyword := firstKeyword(statement); len(keyword) > 0 {
+			tags = tags.With(TagOperation, keyword)
+		}
+	}
+
+	if o.recordArgs {
+		for _, arg := range args {
+			key := arg.Name
+			if len(key) == 0 {
+				key = strconv.Itoa(arg.Ordinal)
+			}
+
+			tags = tags.With(TagArgPrefix+key, arg.Value)
+		}
+	}
+
+	endFn := coretracer.TraceWithName(&ctx, name, tags)
+
+	return ctx, endFn, true
+}
+
+// finish reports the error and ends the span. driver.ErrSkip is not an error: database/sql
+// falls back to prepare the statement, which is traced on its own.
+func finish(ctx context.Context, endFn coretracer.SpanEnderFn, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		coretracer.TraceError(ctx, err)
+	}
+
+	endFn()
+}
+
+// firstKeyword returns the uppercase first word of the sanitized statement, e.g. "SELECT".
+func firstKeyword(statement string) string {
+	statement = strings.TrimLeft(statement, " (")
+
+	end := strings.IndexFunc(statement, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if end < 0 {
+		end = len(statement)
+	}
+
+	return strings.ToUpper(statement[:end])
+}
@@ -0,0 +1,247 @@
+package tracesql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	otelattribute "go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+
+	"github.com/InjectiveLabs/coretracer"
+	"github.com/InjectiveLabs/coretracer/coretracertest"
+)
+
+var errNoRelation = errors.New(`relation "missing" does not exist`)
+
+// fakeDriver is an in-process driver, statements mentioning "missing" fail.
+// Prepared statements implement the legacy methods only.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "missing") {
+		return nil, errNoRelation
+	}
+
+	return fakeStmt{}, nil
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "missing") {
+		return nil, errNoRelation
+	}
+
+	return driver.RowsAffected(2), nil
+}
+
+func (fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "missing") {
+		return nil, errNoRelation
+	}
+
+	return &fakeRows{ids: []int64{1, 2}}, nil
+}
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error {
+	return nil
+}
+
+func (fakeStmt) NumInput() int {
+	return -1
+}
+
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{ids: []int64{1}}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeRows struct {
+	ids []int64
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.ids) == 0 {
+		return io.EOF
+	}
+
+	dest[0], r.ids = r.ids[0], r.ids[1:]
+
+	return nil
+}
+
+var registerOnce, registerTracedOnce sync.Once
+
+func openFakeDB(t *testing.T, opts ...Option) *sql.DB {
+	registerOnce.Do(func() {
+		sql.Register("fakedb", fakeDriver{})
+	})
+
+	db, err := Open("fakedb", "fake://orders", append([]Option{WithSystem("fakedb")}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func countRows(t *testing.T, rows *sql.Rows) int {
+	defer func() { _ = rows.Close() }()
+
+	var n int
+	for rows.Next() {
+		n++
+	}
+
+	require.NoError(t, rows.Err())
+
+	return n
+}
+
+func TestTracing(t *testing.T) {
+	rec := coretracertest.New(t)
+	db := openFakeDB(t)
+
+	ctx := rec.Context()
+	endFn := coretracer.TraceWithName(&ctx, "PlaceOrder")
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM orders WHERE market = 'INJ/USDT' AND id > $1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, countRows(t, rows))
+
+	_, err = db.ExecContext(ctx, "UPDATE orders SET price = 1.5e3 WHERE id = $1", 1)
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx, "DELETE FROM missing")
+	require.ErrorIs(t, err, errNoRelation)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	stmt, err := db.PrepareContext(ctx, "INSERT INTO fills (order_id) VALUES (?)")
+	require.NoError(t, err)
+
+	_, err = stmt.ExecContext(ctx, 42)
+	require.NoError(t, err)
+	require.NoError(t, stmt.Close())
+
+	endFn()
+
+	_, err = db.ExecContext(context.Background(), "UPDATE orders SET price = 2")
+	require.NoError(t, err)
+
+	rec.Span("sql.Query").
+		HasParent("PlaceOrder").
+		HasTag(TagSystem, "fakedb").
+		HasTag(TagStatement, "SELECT id FROM orders WHERE market = ? AND id > $1").
+		HasTag(TagOperation, "SELECT").
+		HasStatusOk()
+
+	execs := rec.SpansNamed(SpanExec)
+	require.Len(t, execs, 3, "Expected statements without a span not to be traced")
+
+	rec.Span(SpanExec).
+		HasParent("PlaceOrder").
+		HasTag(TagStatement, "UPDATE orders SET price = ? WHERE id = $1").
+		HasTag(TagOperation, "UPDATE").
+		HasTag(TagRowsAffected, 2).
+		HasStatusOk()
+
+	rec.Span(SpanTransaction).HasParent("PlaceOrder").HasTag(TagTxOutcome, "commit").HasStatusOk()
+	rec.Span(SpanPrepare).HasParent("PlaceOrder").HasTag(TagOperation, "INSERT")
+
+	failed, prepared := execs[1], execs[2]
+	require.Contains(t, failed.Attributes(), otelattribute.String(TagStatement, "DELETE FROM missing"))
+	require.Equal(t, otelcodes.Error, failed.Status().Code)
+	require.Len(t, failed.Events(), 1)
+
+	require.Contains(t, prepared.Attributes(), otelattribute.String(TagStatement, "INSERT INTO fills (order_id) VALUES (?)"))
+	require.Contains(t, prepared.Attributes(), otelattribute.Int64(TagRowsAffected, 1))
+
+	rec.Span("PlaceOrder").HasChildCount(6)
+}
+
+func TestTracing_Options(t *testing.T) {
+	rec := coretracertest.New(t)
+
+	registerTracedOnce.Do(func() {
+		sql.Register("fakedb-traced", Wrap(fakeDriver{}, WithOperations(OperationExec), WithArgs()))
+	})
+
+	db, err := sql.Open("fakedb-traced", "fake://orders")
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ctx := rec.Context()
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM orders")
+	require.NoError(t, err)
+	require.Equal(t, 2, countRows(t, rows))
+
+	_, err = db.ExecContext(ctx, "UPDATE orders SET market = @market WHERE id = @id",
+		sql.Named("market", "INJ/USDT"),
+		sql.Named("id", 42),
+	)
+	require.NoError(t, err)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	_, err = db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	require.EqualError(t, err, "sql: driver does not support read-only transactions")
+
+	_, err = db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	require.EqualError(t, err, "sql: driver does not support non-default isolation level")
+
+	rec.NoSpan(SpanQuery)
+	rec.NoSpan(SpanTransaction)
+
+	rec.Span(SpanExec).
+		IsRoot().
+		HasTag(TagStatement, "UPDATE orders SET market = @market WHERE id = @id").
+		HasTag(TagArgPrefix+"market", "INJ/USDT").
+		HasTag(TagArgPrefix+"id", 42)
+}